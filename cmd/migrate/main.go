@@ -4,15 +4,25 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/tabwriter"
+	// The time zones are embedded, the binaries are used on systems without a time zone database.
+	_ "time/tzdata"
 
+	"github.com/GustavoCaso/n2o/internal/apicache"
+	"github.com/GustavoCaso/n2o/internal/cassette"
 	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/GustavoCaso/n2o/internal/httpclient"
 	"github.com/GustavoCaso/n2o/internal/log"
 	"github.com/GustavoCaso/n2o/internal/migrator"
+	"github.com/GustavoCaso/n2o/internal/ratelimit"
+	"github.com/GustavoCaso/n2o/internal/state"
 	"github.com/GustavoCaso/n2o/internal/workerpool"
 )
 
@@ -22,6 +32,10 @@ The attributes that support custom formatting are Notion date attributes.
 Example of how to use a Notion date property with custom format as the title for the Obsidian page:
 -name=date:%Y/%B/%d-%A
 
+It also accepts a Go template with every page property, .ID, .CreatedTime, .LastEditedTime
+and the functions date, slug, lower, upper, truncate and join:
+-page-name='{{.Status}}/{{date .Due "%Y-%m"}} - {{.Name | truncate 40}}'
+
 By default if you do not configure any we get the Title property 
 `
 
@@ -29,155 +43,547 @@ var pagePropertiesExplanation = `Notion page properties to convert to Obsidian f
 You can select multiple properties using a comma-separated list.
 `
 
+var propertyMapExplanation = `Rules to write Notion page properties in the Obsidian frontmatter, as a comma-separated list.
+Rename a property with <property>=<key>. Properties renamed to the same key are merged in a list.
+Add select and multi-select values to the Obsidian tags with <property>=tags or <property>=tags:<prefix>,
+to the aliases with <property>=aliases, or drop a property with <property>=-.
+Example: -property-map=Status=state,Category=tags:area/,Nickname=aliases,Internal=-
+`
+
+var filterExplanation = `Only migrate the database pages matching the filter.
+Use conditions on the database properties joined with AND and OR, quoting names and values with spaces:
+-filter='Status = Done AND ("Due date" >= 2024-01-01 OR Tags contains urgent)'
+The comparisons are =, !=, >, >=, <, <=, contains, !contains, starts_with, ends_with, is empty and is not empty.
+It also accepts a Notion filter JSON: -filter='{"property":"Status","status":{"equals":"Done"}}'
+`
+
+var sortExplanation = `Order of the database pages, as a comma-separated list of properties
+followed by asc or desc, or a Notion sorts JSON array. Example: -sort='Date desc, Name'
+`
+
 var notionToken = flag.String("notion-token", os.Getenv("N2O_NOTION_TOKEN"), "Notion token")
 var notionDatabaseID = flag.String("notion-db-ID", os.Getenv("N2O_NOTION_DATABASE_ID"), "Notion database to migrate")
 var notionPageID = flag.String("notion-page-ID", os.Getenv("N2O_NOTION_PAGE_ID"), "Notion page to migrate")
+var workspace = flag.Bool("workspace", false, "migrate every page and database shared with the Notion integration")
 var pagePropertiesList = flag.String("page-properties", "", pagePropertiesExplanation)
 var filenameFromPage = flag.String("page-name", "", filenameFromPageExplanation)
+var propertyMap = flag.String("property-map", "", propertyMapExplanation)
+var filter = flag.String("filter", "", filterExplanation)
+var sorts = flag.String("sort", "", sortExplanation)
 var obsidianVault = flag.String("vault-path", os.Getenv("N2O_OBSIDIAN_VAULT_PATH"), "Obsidian vault location")
 var vaultDestination = flag.String("vault-folder", "", "folder to store pages inside the Obsidian Vault")
 var storeImages = flag.Bool("download-images", false, "download external images to the Obsidian vault")
 var saveToDisk = flag.Bool("save-to-disk", false, "write the pages in the Obsidian vault")
 var debug = flag.Bool("debug", false, "print debug information")
+var recursive = flag.Bool("recursive", false, "migrate the child pages and child databases found in the pages")
+var requestsPerSecond = flag.Float64(
+	"requests-per-second",
+	ratelimit.DefaultRequestsPerSecond,
+	"average number of requests per second sent to the Notion API. 0 disables the limit",
+)
+var rateLimitBurst = flag.Int(
+	"rate-limit-burst",
+	ratelimit.DefaultBurst,
+	"number of requests sent at once to the Notion API before being throttled",
+)
+var maxRetries = flag.Int(
+	"max-retries",
+	ratelimit.DefaultMaxRetries,
+	"number of times a Notion API request is retried when rate limited or failed",
+)
+var syncPages = flag.Bool("sync", false, "only migrate the pages edited since the last run")
+var syncRemoved = flag.String(
+	"sync-removed",
+	config.SyncRemovedKeep,
+	"what to do with the notes of the pages removed from Notion when using -sync: keep, delete or archive",
+)
+var cache = flag.Bool("cache", false, "cache the Notion API responses on disk to speed up the next runs")
+var cacheDir = flag.String(
+	"cache-dir",
+	"",
+	"folder storing the Notion API responses. Default to .n2o/cache inside the vault",
+)
+var cacheTTL = flag.Duration(
+	"cache-ttl",
+	apicache.DefaultTTL,
+	"how long the cached Notion API responses are used. 0 means they never expire",
+)
+var offline = flag.Bool(
+	"offline",
+	false,
+	"migrate the pages from the cached Notion API responses without requesting Notion",
+)
+var record = flag.String(
+	"record",
+	"",
+	"folder to record the Notion API requests and responses, with the Notion token redacted. Used to build test fixtures",
+)
+var notionBaseURL = flag.String(
+	"notion-base-url",
+	"",
+	"URL of the Notion API, for example a local stand-in of the Notion API. Default to "+httpclient.NotionBaseURL,
+)
+var proxy = flag.String(
+	"proxy",
+	"",
+	"URL of the HTTP proxy for the Notion API and the images. Default to the HTTP_PROXY and HTTPS_PROXY variables",
+)
+var caBundle = flag.String("ca-bundle", "", "PEM file with extra certificate authorities to trust")
+var requestTimeout = flag.Duration(
+	"request-timeout",
+	httpclient.DefaultTimeout,
+	"timeout of every request to the Notion API and image download. 0 means no timeout",
+)
+var userAgent = flag.String("user-agent", httpclient.DefaultUserAgent, "User-Agent of the HTTP requests")
+var dateFormat = flag.String(
+	"date-format",
+	config.DefaultDateFormat,
+	"strftime format of the dates in page names and date mentions, used when -page-name does not set one",
+)
+var dateRange = flag.String(
+	"date-range",
+	config.DateRangeInterval,
+	"how date ranges are written in the frontmatter: interval (start/end) or split (<key>_start and <key>_end)",
+)
+var timeZone = flag.String(
+	"time-zone",
+	"",
+	"IANA time zone the dates with time are converted to, for example Europe/Madrid. Default to the Notion time zone",
+)
+var filenameReplace = flag.String(
+	"filename-replace",
+	"",
+	"comma-separated replacements for the note names, added to the defaults, for example ':= -,&=and'",
+)
+var maxFilenameLength = flag.Int(
+	"max-filename-length",
+	config.DefaultMaxFilenameLength,
+	"maximum length in bytes of the note and folder names built from Notion titles",
+)
+var calloutMap = flag.String(
+	"callout-map",
+	"",
+	"comma-separated Obsidian callout types of the Notion callout icons and colors, added to the defaults, "+
+		"for example '🔥=danger,purple=abstract'",
+)
+var toggles = flag.String(
+	"toggles",
+	config.TogglesList,
+	"how toggles and toggleable headings are written: list, callout (folded Obsidian callouts) or details (HTML)",
+)
+var recursiveDepth = flag.Int(
+	"recursive-depth",
+	config.DefaultRecursiveDepth,
+	"how many levels of child pages to migrate with -recursive. 0 means no limit",
+)
+
+var configFile = flag.String("config", "", "YAML or TOML file listing the Notion databases and pages to migrate")
 
 func main() {
 	flag.Parse()
 
 	logger := log.New(os.Stdout)
 
-	if empty(notionToken) {
+	configs, err := loadConfigs()
+	if err != nil {
 		flag.Usage()
-		logger.Warn("You must provide the notion token")
+		logger.Warn(err.Error())
 		os.Exit(1)
 	}
 
-	if empty(notionDatabaseID) && empty(notionPageID) {
-		flag.Usage()
-		logger.Warn("You must provide a notion database ID or a page ID")
-		os.Exit(1)
-	}
+	ctx := context.Background()
+	buf := &bytes.Buffer{}
+	migratorLogger := log.New(buf)
 
-	if !empty(notionDatabaseID) && !empty(notionPageID) {
-		flag.Usage()
-		logger.Warn("You must provide a notion database ID or a page ID not both")
+	// All the migrators share the same cache so pages referenced between sources become links
+	// instead of being downloaded again.
+	cache := migrator.NewCache()
+	// The Notion API rate limit is per integration, the HTTP client is shared between the sources to respect it.
+	notionHTTPClient, err := migrator.NewNotionHTTPClient(configs[0])
+	if err != nil {
+		logger.Error(err.Error())
 		os.Exit(1)
 	}
-
-	pageProperties := map[string]bool{}
-
-	if !empty(pagePropertiesList) {
-		results := strings.Split(*pagePropertiesList, ",")
-		for _, prop := range results {
-			pageProperties[prop] = true
-		}
-	}
-
-	if empty(obsidianVault) {
-		flag.Usage()
-		logger.Warn("You must provide the Obisidian vault path")
+	// The images are downloaded through the same proxy and with the same timeout as the Notion API requests.
+	httpClient, err := migrator.NewHTTPClient(configs[0])
+	if err != nil {
+		logger.Error(err.Error())
 		os.Exit(1)
 	}
-
-	pageNameFilters := map[string]string{}
-	if !empty(filenameFromPage) {
-		pagePathResults := strings.Split(*filenameFromPage, ",")
-		for _, pagePathAttribute := range pagePathResults {
-			pageWithFormatOptions := strings.Split(pagePathAttribute, ":")
-			if len(pageWithFormatOptions) > 1 {
-				pageNameFilters[strings.ToLower(pageWithFormatOptions[0])] = pageWithFormatOptions[1]
-			} else {
-				pageNameFilters[strings.ToLower(pageWithFormatOptions[0])] = ""
-			}
+	// The interactions are written to the cassette as they happen, so it is usable even if the run fails.
+	var recorder *cassette.Recorder
+	if !empty(record) {
+		recorder, err = cassette.NewRecorder(notionHTTPClient.Transport, *record, configs[0].Token)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
 		}
+		notionHTTPClient.Transport = recorder
 	}
-
-	config := &config.Config{
-		Token:                   *notionToken,
-		DatabaseID:              *notionDatabaseID,
-		PageID:                  *notionPageID,
-		StoreImages:             *storeImages,
-		PageNameFilters:         pageNameFilters,
-		PagePropertiesToMigrate: pageProperties,
-		VaultPath:               *obsidianVault,
-		VaultDestination:        *vaultDestination,
-		SaveToDisk:              *saveToDisk,
-		Debug:                   *debug,
-	}
-
-	ctx := context.Background()
-	buf := &bytes.Buffer{}
-	migratorLogger := log.New(buf)
-
-	migrator := migrator.NewMigrator(config, migrator.NewCache(), migratorLogger)
-
-	pages, err := migrator.FetchPages(ctx)
+	// The state records the pages written to the vault, every source is migrated to the same vault.
+	vaultState, err := state.Load(configs[0].VaultPath)
 	if err != nil {
-		logger.Error(fmt.Sprintf("an error ocurred when fetching page. error: %v\n", err))
+		logger.Error(err.Error())
 		os.Exit(1)
 	}
+	migrators := make([]migrator.Migrator, len(configs))
 
 	var jobs []*workerpool.Job
 
 	pool := workerpool.New("fetching notion pages information", 10, workerpool.WithProgressBar())
 
-	for _, page := range pages {
-		// We need to do this, because variables declared inside for loops are passed by reference.
-		// Otherwise, our closure will always receive the last item from the page.
-		newPage := page
+	for i, config := range configs {
+		sourceMigrator, err := migrator.NewMigrator(
+			config,
+			cache,
+			migratorLogger,
+			migrator.WithNotionHTTPClient(notionHTTPClient),
+			migrator.WithHTTPClient(httpClient),
+			migrator.WithState(vaultState),
+		)
+		if err != nil {
+			logger.Error(err.Error())
+			os.Exit(1)
+		}
+		migrators[i] = sourceMigrator
 
-		job := &workerpool.Job{
-			Path: newPage.Path,
-			Run: func() {
-				err := migrator.FetchParseAndSavePage(ctx, newPage, config.PagePropertiesToMigrate)
-				if err != nil {
-					migratorLogger.Error(
-						fmt.Sprintf("an error ocurred when processing a page %s. error: %v", newPage.Path, err),
-					)
-				}
-			},
+		pages, err := sourceMigrator.FetchPages(ctx)
+		if err != nil {
+			logger.Error(fmt.Sprintf("an error ocurred when fetching page. error: %v\n", err))
+			os.Exit(1)
 		}
 
-		jobs = append(jobs, job)
+		for _, page := range pages {
+			// We need to do this, because variables declared inside for loops are passed by reference.
+			// Otherwise, our closure will always receive the last item from the page.
+			newPage := page
+
+			job := &workerpool.Job{
+				Path: newPage.Path,
+				ID:   newPage.ID(),
+				Run: func() error {
+					return sourceMigrator.FetchParseAndSavePage(ctx, newPage, config.PagePropertiesToMigrate)
+				},
+			}
+
+			jobs = append(jobs, job)
+		}
 	}
 
 	// enequeue page to download and parse
 	pool.AddJobs(jobs)
 
 	// blocking operation
-	pool.DoWork(ctx)
+	failures := pool.DoWork(ctx)
 
 	migratorLogs, _ := io.ReadAll(buf)
 
 	fmt.Fprint(os.Stdout, string(migratorLogs))
 
-	if config.SaveToDisk {
+	// The failed pages are listed before asking to write the pages, they are not written.
+	if len(failures) > 0 {
+		printFailures(os.Stdout, failures)
+		logger.Error(fmt.Sprintf("%d of %d pages could not be migrated", len(failures), len(jobs)))
+	}
+
+	if configs[0].SaveToDisk {
 		logger.Info("Saving pages to the Obsidian vault")
-		err := migrator.WritePagesToDisk(ctx)
-		if err != nil {
-			logger.Error(fmt.Sprintf("an error ocurred when writing pages to the Obsidian vault. error: %v\n", err))
-			os.Exit(1)
-		}
+		writePagesToDisk(ctx, logger, migrators, vaultState)
 	} else {
 		logger.Info("Displaying the pages that would be created in your vault")
-		err := migrator.DisplayInformation(ctx)
-		if err != nil {
-			logger.Error(fmt.Sprintf("an error ocurred when displaying the pages information. error: %v\n", err))
-			os.Exit(1)
+		for _, m := range migrators {
+			err := m.DisplayInformation(ctx)
+			if err != nil {
+				logger.Error(fmt.Sprintf("an error ocurred when displaying the pages information. error: %v\n", err))
+				os.Exit(1)
+			}
 		}
 		reader := bufio.NewReader(os.Stdin)
 		fmt.Println("Do you want to write the pages to the Obsidian vault? y/n")
 		text, _ := reader.ReadString('\n')
 		if strings.Contains(strings.ToLower(text), "y") {
 			logger.Info("Saving pages to the Obsidian vault")
-			err := migrator.WritePagesToDisk(ctx)
+			writePagesToDisk(ctx, logger, migrators, vaultState)
+		}
+	}
+
+	if recorder != nil {
+		if err = recorder.Close(); err != nil {
+			logger.Error(fmt.Sprintf("an error ocurred when saving the recorded Notion API requests. error: %v\n", err))
+			os.Exit(1)
+		}
+		logger.Info(fmt.Sprintf("Notion API requests recorded in %s", filepath.Join(*record, cassette.FileName)))
+	}
+
+	// Scheduled migrations rely on the exit code to report the pages that could not be migrated.
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+
+	logger.Info("Done 🎉")
+}
+
+// printFailures writes a table with the pages that could not be migrated, their Notion ID and the reason.
+func printFailures(w io.Writer, failures []*workerpool.ErrJob) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(table, "PAGE\tNOTION ID\tERROR")
+	for _, failure := range failures {
+		reason := strings.Join(strings.Fields(failure.Err.Error()), " ")
+		fmt.Fprintf(table, "%s\t%s\t%s\n", failure.Job.Path, failure.Job.ID, reason)
+	}
+
+	table.Flush()
+}
+
+func writePagesToDisk(ctx context.Context, logger log.Log, migrators []migrator.Migrator, vaultState *state.State) {
+	for _, m := range migrators {
+		err := m.WritePagesToDisk(ctx)
+		if err != nil {
+			logger.Error(fmt.Sprintf("an error ocurred when writing pages to the Obsidian vault. error: %v\n", err))
+			os.Exit(1)
+		}
+	}
+
+	if err := vaultState.Save(); err != nil {
+		logger.Error(fmt.Sprintf("an error ocurred when saving the sync state. error: %v\n", err))
+		os.Exit(1)
+	}
+}
+
+// loadConfigs returns one configuration per Notion source to migrate.
+// When a config file is provided the command line flags act as defaults for the shared settings.
+func loadConfigs() ([]*config.Config, error) {
+	if !empty(configFile) {
+		file, err := config.LoadFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if file.Token == "" {
+			file.Token = *notionToken
+		}
+		if file.VaultPath == "" {
+			file.VaultPath = *obsidianVault
+		}
+		file.DownloadImages = file.DownloadImages || *storeImages
+		file.SaveToDisk = file.SaveToDisk || *saveToDisk
+		file.Debug = file.Debug || *debug
+		if file.RequestsPerSecond == nil {
+			file.RequestsPerSecond = requestsPerSecond
+		}
+		if file.RateLimitBurst == nil {
+			file.RateLimitBurst = rateLimitBurst
+		}
+		if file.MaxRetries == nil {
+			file.MaxRetries = maxRetries
+		}
+		file.Sync = file.Sync || *syncPages
+		if file.SyncRemoved == "" {
+			file.SyncRemoved = *syncRemoved
+		}
+		file.Cache = file.Cache || *cache
+		if file.CacheDir == "" {
+			file.CacheDir = *cacheDir
+		}
+		if file.CacheTTL == nil {
+			ttl := config.Duration(*cacheTTL)
+			file.CacheTTL = &ttl
+		}
+		file.Offline = file.Offline || *offline
+		if file.NotionBaseURL == "" {
+			file.NotionBaseURL = *notionBaseURL
+		}
+		if file.Proxy == "" {
+			file.Proxy = *proxy
+		}
+		if file.CABundle == "" {
+			file.CABundle = *caBundle
+		}
+		if file.RequestTimeout == nil {
+			timeout := config.Duration(*requestTimeout)
+			file.RequestTimeout = &timeout
+		}
+		if file.UserAgent == "" {
+			file.UserAgent = *userAgent
+		}
+		if file.DateFormat == "" {
+			file.DateFormat = *dateFormat
+		}
+		if file.DateRange == "" {
+			file.DateRange = *dateRange
+		}
+		if file.TimeZone == "" {
+			file.TimeZone = *timeZone
+		}
+		if file.FilenameReplace == nil {
+			file.FilenameReplace, err = config.ParseFilenameReplacements(*filenameReplace)
 			if err != nil {
-				logger.Error(fmt.Sprintf("an error ocurred when writing pages to the Obsidian vault. error: %v\n", err))
-				os.Exit(1)
+				return nil, err
+			}
+		}
+		if file.MaxFilenameLength == 0 {
+			file.MaxFilenameLength = *maxFilenameLength
+		}
+		if file.CalloutMap == nil {
+			file.CalloutMap, err = config.ParseCalloutTypes(*calloutMap)
+			if err != nil {
+				return nil, err
 			}
 		}
+		if file.Toggles == "" {
+			file.Toggles = *toggles
+		}
+		file.Recursive = file.Recursive || *recursive
+		if file.RecursiveDepth == nil {
+			file.RecursiveDepth = recursiveDepth
+		}
+
+		if err = file.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s:\n%w", *configFile, err)
+		}
+
+		return file.Configs(), nil
 	}
 
-	logger.Info("Done 🎉")
+	if empty(notionToken) {
+		return nil, errors.New("You must provide the notion token")
+	}
+
+	if *workspace && (!empty(notionDatabaseID) || !empty(notionPageID)) {
+		return nil, errors.New("You must provide a notion database ID, a page ID or -workspace not more than one")
+	}
+
+	if empty(notionDatabaseID) && empty(notionPageID) && !*workspace {
+		return nil, errors.New("You must provide a notion database ID or a page ID")
+	}
+
+	if !empty(notionDatabaseID) && !empty(notionPageID) {
+		return nil, errors.New("You must provide a notion database ID or a page ID not both")
+	}
+
+	if empty(notionDatabaseID) && (!empty(filter) || !empty(sorts)) {
+		return nil, errors.New("You can only filter and sort the pages of a notion database")
+	}
+
+	if empty(obsidianVault) {
+		return nil, errors.New("You must provide the Obisidian vault path")
+	}
+
+	if *recursiveDepth < 0 {
+		return nil, errors.New("The recursive depth must be zero or a positive number")
+	}
+
+	if *requestsPerSecond < 0 || *maxRetries < 0 {
+		return nil, errors.New("The Notion API limits must be zero or positive numbers")
+	}
+
+	if *rateLimitBurst < 1 {
+		return nil, errors.New("The rate limit burst must be a positive number")
+	}
+
+	if err := config.ValidateSyncRemoved(*syncRemoved); err != nil {
+		return nil, err
+	}
+
+	if *cacheTTL < 0 {
+		return nil, errors.New("The cache TTL must be zero or a positive duration")
+	}
+
+	propertyMappings, err := config.ParsePropertyMappings(*propertyMap)
+	if err != nil {
+		return nil, err
+	}
+
+	if *requestTimeout < 0 {
+		return nil, errors.New("The request timeout must be zero or a positive duration")
+	}
+
+	if err := config.ValidateURL(*notionBaseURL); err != nil {
+		return nil, fmt.Errorf("Invalid Notion base URL: %w", err)
+	}
+
+	if err := config.ValidateURL(*proxy); err != nil {
+		return nil, fmt.Errorf("Invalid proxy: %w", err)
+	}
+
+	if err := config.ValidateDateRange(*dateRange); err != nil {
+		return nil, err
+	}
+
+	if err := config.ValidateToggles(*toggles); err != nil {
+		return nil, err
+	}
+
+	location, err := config.LoadTimeZone(*timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	filenameReplacements, err := config.ParseFilenameReplacements(*filenameReplace)
+	if err != nil {
+		return nil, err
+	}
+
+	if *maxFilenameLength < 0 {
+		return nil, errors.New("The maximum filename length must be zero or a positive number")
+	}
+
+	calloutTypes, err := config.ParseCalloutTypes(*calloutMap)
+	if err != nil {
+		return nil, err
+	}
+
+	pageNameFilters := map[string]string{}
+	pageNameTemplate := ""
+	if config.IsPageNameTemplate(*filenameFromPage) {
+		pageNameTemplate = *filenameFromPage
+	} else {
+		pageNameFilters = config.ParsePageNameFilters(*filenameFromPage)
+	}
+
+	return []*config.Config{
+		{
+			Token:                   *notionToken,
+			DatabaseID:              *notionDatabaseID,
+			PageID:                  *notionPageID,
+			Workspace:               *workspace,
+			StoreImages:             *storeImages,
+			PageNameFilters:         pageNameFilters,
+			PageNameTemplate:        pageNameTemplate,
+			Filter:                  *filter,
+			Sorts:                   *sorts,
+			FilenameReplacements:    filenameReplacements,
+			MaxFilenameLength:       *maxFilenameLength,
+			CalloutTypes:            calloutTypes,
+			Toggles:                 *toggles,
+			DateFormat:              *dateFormat,
+			DateRange:               *dateRange,
+			TimeZone:                location,
+			PagePropertiesToMigrate: config.ParsePageProperties(*pagePropertiesList),
+			PropertyMappings:        propertyMappings,
+			VaultPath:               *obsidianVault,
+			VaultDestination:        *vaultDestination,
+			SaveToDisk:              *saveToDisk,
+			Debug:                   *debug,
+			Recursive:               *recursive,
+			RecursiveDepth:          *recursiveDepth,
+			RequestsPerSecond:       *requestsPerSecond,
+			RateLimitBurst:          *rateLimitBurst,
+			MaxRetries:              *maxRetries,
+			Sync:                    *syncPages,
+			SyncRemoved:             *syncRemoved,
+			Cache:                   *cache,
+			CacheDir:                *cacheDir,
+			CacheTTL:                *cacheTTL,
+			Offline:                 *offline,
+			NotionBaseURL:           *notionBaseURL,
+			Proxy:                   *proxy,
+			CABundle:                *caBundle,
+			RequestTimeout:          *requestTimeout,
+			UserAgent:               *userAgent,
+		},
+	}, nil
 }
 
 func empty(v *string) bool {
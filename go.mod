@@ -3,10 +3,12 @@ module github.com/GustavoCaso/n2o
 go 1.25
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/dstotijn/go-notion v0.11.0
 	github.com/itchyny/timefmt-go v0.1.5
 	github.com/schollz/progressbar/v3 v3.14.1
 	github.com/stretchr/testify v1.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
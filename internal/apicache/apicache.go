@@ -0,0 +1,309 @@
+package apicache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultTTL = 24 * time.Hour
+
+// ErrNotCached is returned in offline mode for the requests missing from the cache.
+var ErrNotCached = errors.New("response not found in the cache")
+
+type Options struct {
+	// Dir is the folder storing the cached responses.
+	Dir string
+	// TTL is how long a cached response is used before requesting it again. Zero means responses never expire.
+	TTL time.Duration
+	// Offline serves every request from the cache, without validating the cached responses.
+	Offline bool
+}
+
+// Transport is an http.RoundTripper caching the Notion API responses on disk.
+//
+// Responses are keyed by endpoint, object ID and request body. A cached response is used while it is
+// younger than the TTL and the object it belongs to has not been edited: every page and block returned
+// by the API records its last_edited_time, and the cached responses of an object edited since are requested again.
+// Database queries and searches are the source of those last_edited_time, so they are only cached for offline runs.
+type Transport struct {
+	next    http.RoundTripper
+	options Options
+	now     func() time.Time
+
+	mu sync.Mutex
+	// edited holds the last_edited_time of the objects seen in the responses of this run.
+	edited map[string]string
+}
+
+type entry struct {
+	Method         string          `json:"method"`
+	URL            string          `json:"url"`
+	StatusCode     int             `json:"status_code"`
+	Header         http.Header     `json:"header"`
+	Body           json.RawMessage `json:"body"`
+	StoredAt       time.Time       `json:"stored_at"`
+	ObjectID       string          `json:"object_id,omitempty"`
+	LastEditedTime string          `json:"last_edited_time,omitempty"`
+}
+
+// object is the part of a Notion object or list used to validate the cache.
+type object struct {
+	ID             string   `json:"id"`
+	LastEditedTime string   `json:"last_edited_time"`
+	Results        []object `json:"results"`
+}
+
+func NewTransport(next http.RoundTripper, options Options) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	return &Transport{
+		next:    next,
+		options: options,
+		now:     time.Now,
+		edited:  map[string]string{},
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resource, objectID, cacheable := endpoint(req)
+	if !cacheable {
+		if t.options.Offline {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, ErrNotCached)
+		}
+		return t.next.RoundTrip(req)
+	}
+
+	body, req, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	file := t.path(resource, req, body)
+
+	cached, err := t.load(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != nil && (t.options.Offline || t.valid(resource, objectID, cached)) {
+		t.record(cached.Body)
+		return cached.response(req), nil
+	}
+
+	if t.options.Offline {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.Path, ErrNotCached)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	if !json.Valid(respBody) {
+		return resp, nil
+	}
+
+	t.record(respBody)
+
+	stored := &entry{
+		Method:     req.Method,
+		URL:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       respBody,
+		StoredAt:   t.now(),
+		ObjectID:   objectID,
+	}
+	stored.LastEditedTime = t.lastEditedTime(objectID)
+
+	if err = t.store(file, stored); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// valid reports if a cached response can be used without requesting it again.
+func (t *Transport) valid(resource, objectID string, cached *entry) bool {
+	if resource == "search" || resource == "query" {
+		return false
+	}
+
+	if t.options.TTL > 0 && t.now().Sub(cached.StoredAt) > t.options.TTL {
+		return false
+	}
+
+	if edited := t.lastEditedTime(objectID); edited != "" && edited != cached.LastEditedTime {
+		return false
+	}
+
+	return true
+}
+
+// record saves the last_edited_time of the objects in a response.
+func (t *Transport) record(body []byte) {
+	o := object{}
+	if err := json.Unmarshal(body, &o); err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, result := range append(o.Results, o) {
+		if result.ID != "" && result.LastEditedTime != "" {
+			t.edited[normalizeID(result.ID)] = result.LastEditedTime
+		}
+	}
+}
+
+func (t *Transport) lastEditedTime(objectID string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.edited[objectID]
+}
+
+func (t *Transport) path(resource string, req *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s?%s\n", req.Method, req.URL.Path, req.URL.RawQuery)
+	hash.Write(body)
+
+	return filepath.Join(t.options.Dir, resource, hex.EncodeToString(hash.Sum(nil))+".json")
+}
+
+func (t *Transport) load(file string) (*entry, error) {
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the cached response %s. error: %w", file, err)
+	}
+
+	cached := &entry{}
+	if err = json.Unmarshal(content, cached); err != nil {
+		// A corrupted entry is requested again and overwritten.
+		return nil, nil
+	}
+
+	return cached, nil
+}
+
+// store writes the entry atomically, concurrent requests for the same response do not read a partial file.
+func (t *Transport) store(file string, stored *entry) error {
+	content, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode the cached response. error: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+		return fmt.Errorf("failed to create the cache folder. error: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), "*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create the cached response. error: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write the cached response. error: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write the cached response. error: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to store the cached response %s. error: %w", file, err)
+	}
+
+	return nil
+}
+
+func (e *entry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// endpoint returns the resource and the object ID of a request, and if its response can be cached.
+// Only the read endpoints are cached: retrieving objects, listing block children,
+// querying databases and searching.
+func endpoint(req *http.Request) (string, string, bool) {
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	// Drop the API version prefix.
+	if len(segments) > 0 && segments[0] == "v1" {
+		segments = segments[1:]
+	}
+
+	switch {
+	case req.Method == http.MethodPost && len(segments) == 1 && segments[0] == "search":
+		return "search", "", true
+	case req.Method == http.MethodPost && len(segments) == 3 && segments[0] == "databases" && segments[2] == "query":
+		return "query", normalizeID(segments[1]), true
+	case req.Method == http.MethodGet && len(segments) >= 2:
+		return segments[0], normalizeID(segments[1]), true
+	default:
+		return "", "", false
+	}
+}
+
+// readBody returns the request body and a copy of the request that can still be sent.
+func readBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read the request body. error: %w", err)
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return body, clone, nil
+}
+
+// normalizeID removes the dashes from a Notion ID, the API accepts and returns both forms.
+func normalizeID(id string) string {
+	return strings.ReplaceAll(id, "-", "")
+}
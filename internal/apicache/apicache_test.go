@@ -0,0 +1,184 @@
+package apicache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRoundtripper struct {
+	fn func(*http.Request) (*http.Response, error)
+}
+
+func (m *mockRoundtripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return m.fn(r)
+}
+
+// fakeNotion answers with a page edited at lastEditedTime and counts the requests per URL.
+type fakeNotion struct {
+	lastEditedTime string
+	requests       map[string]int
+}
+
+func (f *fakeNotion) RoundTrip(r *http.Request) (*http.Response, error) {
+	f.requests[r.Method+" "+r.URL.Path]++
+
+	var body string
+	switch r.URL.Path {
+	case "/v1/databases/000000/query":
+		body = fmt.Sprintf(
+			`{"object":"list","results":[{"object":"page","id":"1111-1111","last_edited_time":%q}]}`,
+			f.lastEditedTime,
+		)
+	case "/v1/blocks/11111111/children":
+		body = `{"object":"list","results":[{"object":"block","id":"2222","last_edited_time":"2024-10-10T10:00:00.000Z"}]}`
+	case "/v1/users/3333":
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+	default:
+		panic(fmt.Sprintf("unhandled URL: %s", r.URL.String()))
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}, nil
+}
+
+func send(t *testing.T, transport http.RoundTripper, method, url string) (*http.Response, error) {
+	var body io.Reader
+	if method == http.MethodPost {
+		body = bytes.NewBufferString(`{"page_size":100}`)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), method, url, body)
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	if err == nil {
+		_, readErr := io.ReadAll(resp.Body)
+		require.NoError(t, readErr)
+		resp.Body.Close()
+	}
+
+	return resp, err
+}
+
+// migrate sends the requests of a migration: listing the database and fetching the page content.
+func migrate(t *testing.T, transport http.RoundTripper) {
+	resp, err := send(t, transport, http.MethodPost, "https://api.notion.com/v1/databases/000000/query")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = send(t, transport, http.MethodGet, "https://api.notion.com/v1/blocks/11111111/children")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name             string
+		ttl              time.Duration
+		elapsed          time.Duration
+		lastEditedTime   string
+		expectedRequests map[string]int
+	}{
+		{
+			name:           "uses the cached responses of pages not edited",
+			ttl:            time.Hour,
+			elapsed:        time.Minute,
+			lastEditedTime: "2024-10-10T10:00:00.000Z",
+			expectedRequests: map[string]int{
+				"POST /v1/databases/000000/query":  2,
+				"GET /v1/blocks/11111111/children": 1,
+			},
+		},
+		{
+			name:           "requests again the content of edited pages",
+			ttl:            time.Hour,
+			elapsed:        time.Minute,
+			lastEditedTime: "2024-10-11T10:00:00.000Z",
+			expectedRequests: map[string]int{
+				"POST /v1/databases/000000/query":  2,
+				"GET /v1/blocks/11111111/children": 2,
+			},
+		},
+		{
+			name:           "requests again the expired responses",
+			ttl:            time.Hour,
+			elapsed:        2 * time.Hour,
+			lastEditedTime: "2024-10-10T10:00:00.000Z",
+			expectedRequests: map[string]int{
+				"POST /v1/databases/000000/query":  2,
+				"GET /v1/blocks/11111111/children": 2,
+			},
+		},
+		{
+			name:           "responses never expire without TTL",
+			elapsed:        24 * 365 * time.Hour,
+			lastEditedTime: "2024-10-10T10:00:00.000Z",
+			expectedRequests: map[string]int{
+				"POST /v1/databases/000000/query":  2,
+				"GET /v1/blocks/11111111/children": 1,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			notion := &fakeNotion{lastEditedTime: "2024-10-10T10:00:00.000Z", requests: map[string]int{}}
+			now := time.Date(2024, 10, 12, 10, 0, 0, 0, time.UTC)
+
+			first := NewTransport(notion, Options{Dir: dir, TTL: test.ttl})
+			first.now = func() time.Time { return now }
+			migrate(t, first)
+
+			// A new run, with the page edited in Notion.
+			notion.lastEditedTime = test.lastEditedTime
+			second := NewTransport(notion, Options{Dir: dir, TTL: test.ttl})
+			second.now = func() time.Time { return now.Add(test.elapsed) }
+			migrate(t, second)
+
+			assert.Equal(t, test.expectedRequests, notion.requests)
+		})
+	}
+}
+
+func TestRoundTrip_Offline(t *testing.T) {
+	dir := t.TempDir()
+	notion := &fakeNotion{lastEditedTime: "2024-10-10T10:00:00.000Z", requests: map[string]int{}}
+
+	migrate(t, NewTransport(notion, Options{Dir: dir, TTL: time.Nanosecond}))
+
+	offline := NewTransport(&mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+		panic(fmt.Sprintf("offline request sent: %s", r.URL.String()))
+	}}, Options{Dir: dir, TTL: time.Nanosecond, Offline: true})
+
+	migrate(t, offline)
+
+	_, err := send(t, offline, http.MethodGet, "https://api.notion.com/v1/pages/4444")
+	assert.True(t, errors.Is(err, ErrNotCached), "unexpected error %v", err)
+}
+
+func TestRoundTrip_DoesNotCacheErrors(t *testing.T) {
+	notion := &fakeNotion{requests: map[string]int{}}
+	transport := NewTransport(notion, Options{Dir: t.TempDir(), TTL: time.Hour})
+
+	for range 2 {
+		resp, err := send(t, transport, http.MethodGet, "https://api.notion.com/v1/users/3333")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	}
+
+	assert.Equal(t, 2, notion.requests["GET /v1/users/3333"])
+}
@@ -0,0 +1,244 @@
+package cassette
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileName is the cassette written inside the record folder.
+const FileName = "cassette.jsonl"
+
+// Redacted replaces the Notion token in the recorded interactions.
+const Redacted = "[REDACTED]"
+
+// Interaction is a request sent to the Notion API and its response.
+// A cassette stores one interaction per line, in the order they were sent.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body is stored as JSON when possible so cassettes are easy to read and edit, otherwise as a JSON string.
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if len(b) == 0 {
+		return []byte(`null`), nil
+	}
+
+	if json.Valid(b) && !bytes.HasPrefix(bytes.TrimSpace(b), []byte(`"`)) {
+		compacted := &bytes.Buffer{}
+		if err := json.Compact(compacted, b); err != nil {
+			return nil, err
+		}
+		return compacted.Bytes(), nil
+	}
+
+	return json.Marshal(string(b))
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	switch {
+	case bytes.Equal(data, []byte(`null`)):
+		*b = nil
+	case bytes.HasPrefix(data, []byte(`"`)):
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*b = Body(s)
+	default:
+		*b = append(Body{}, data...)
+	}
+
+	return nil
+}
+
+// Load reads the interactions of a cassette.
+func Load(path string) ([]Interaction, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the cassette %s. error: %w", path, err)
+	}
+	defer f.Close()
+
+	interactions := []Interaction{}
+
+	scanner := bufio.NewScanner(f)
+	// Notion responses can be larger than the default buffer.
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		interaction := Interaction{}
+		if err = json.Unmarshal(scanner.Bytes(), &interaction); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of the cassette %s. error: %w", line, path, err)
+		}
+		interactions = append(interactions, interaction)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read the cassette %s. error: %w", path, err)
+	}
+
+	return interactions, nil
+}
+
+// Recorder is an http.RoundTripper writing every request and its response to a cassette.
+// The Notion token is redacted from the recorded headers, URLs and bodies.
+type Recorder struct {
+	next  http.RoundTripper
+	token string
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRecorder creates the cassette inside dir, replacing a previous recording.
+func NewRecorder(next http.RoundTripper, dir, token string) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create the record folder %s. error: %w", dir, err)
+	}
+
+	path := filepath.Join(dir, FileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the cassette %s. error: %w", path, err)
+	}
+
+	return &Recorder{
+		next:  next,
+		token: token,
+		file:  file,
+	}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the request body. error: %w", err)
+		}
+		reqBody = body
+
+		clone := req.Clone(req.Context())
+		clone.Body = io.NopCloser(bytes.NewReader(body))
+		clone.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req = clone
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body. error: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    r.redact(req.URL.String()),
+			Header: r.redactHeader(req.Header),
+			Body:   Body(r.redact(string(reqBody))),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+			Body:       Body(r.redact(string(respBody))),
+		},
+	}
+
+	if err = r.write(interaction); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Close closes the cassette.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// write appends the interaction to the cassette right away, so an interrupted run keeps what was recorded.
+func (r *Recorder) write(interaction Interaction) error {
+	line, err := json.Marshal(interaction)
+	if err != nil {
+		return fmt.Errorf("failed to encode the recorded interaction. error: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err = r.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write the cassette. error: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Recorder) redactHeader(header http.Header) http.Header {
+	redacted := http.Header{}
+
+	for key, values := range header {
+		switch http.CanonicalHeaderKey(key) {
+		case "Authorization":
+			redacted.Set(key, "Bearer "+Redacted)
+		case "Cookie", "Set-Cookie":
+			continue
+		default:
+			for _, value := range values {
+				redacted.Add(key, r.redact(value))
+			}
+		}
+	}
+
+	return redacted
+}
+
+func (r *Recorder) redact(s string) string {
+	if r.token == "" {
+		return s
+	}
+
+	return strings.ReplaceAll(s, r.token, Redacted)
+}
@@ -0,0 +1,106 @@
+package cassette
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRoundtripper struct {
+	fn func(*http.Request) (*http.Response, error)
+}
+
+func (m *mockRoundtripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return m.fn(r)
+}
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+
+	recorder, err := NewRecorder(&mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+		body, readErr := io.ReadAll(r.Body)
+		require.NoError(t, readErr)
+
+		switch r.URL.Path {
+		case "/v1/databases/000000/query":
+			assert.JSONEq(t, `{"page_size": 100}`, string(body))
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Set-Cookie": []string{"session=secret-token"}},
+				Body:       io.NopCloser(strings.NewReader(`{"object": "list", "results": []}`)),
+			}, nil
+		default:
+			return &http.Response{
+				StatusCode: http.StatusBadGateway,
+				Body:       io.NopCloser(strings.NewReader(`<html>bad gateway for secret-token</html>`)),
+			}, nil
+		}
+	}}, dir, "secret-token")
+	require.NoError(t, err)
+
+	send := func(method, url, body string) string {
+		req, reqErr := http.NewRequestWithContext(context.Background(), method, url, bytes.NewBufferString(body))
+		require.NoError(t, reqErr)
+		req.Header.Set("Authorization", "Bearer secret-token")
+
+		resp, reqErr := recorder.RoundTrip(req)
+		require.NoError(t, reqErr)
+		defer resp.Body.Close()
+
+		respBody, reqErr := io.ReadAll(resp.Body)
+		require.NoError(t, reqErr)
+		return string(respBody)
+	}
+
+	assert.Equal(t, `{"object": "list", "results": []}`, send(
+		http.MethodPost,
+		"https://api.notion.com/v1/databases/000000/query",
+		`{"page_size": 100}`,
+	))
+	assert.Equal(t, `<html>bad gateway for secret-token</html>`, send(
+		http.MethodGet,
+		"https://api.notion.com/v1/pages/111111",
+		"",
+	))
+	require.NoError(t, recorder.Close())
+
+	content, err := os.ReadFile(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret-token")
+	assert.Equal(t, 2, strings.Count(string(content), "\n"))
+
+	interactions, err := Load(filepath.Join(dir, FileName))
+	require.NoError(t, err)
+	require.Len(t, interactions, 2)
+
+	query := interactions[0]
+	assert.Equal(t, http.MethodPost, query.Request.Method)
+	assert.Equal(t, "https://api.notion.com/v1/databases/000000/query", query.Request.URL)
+	assert.Equal(t, "Bearer [REDACTED]", query.Request.Header.Get("Authorization"))
+	assert.Equal(t, `{"page_size":100}`, string(query.Request.Body))
+	assert.Equal(t, http.StatusOK, query.Response.StatusCode)
+	assert.Empty(t, query.Response.Header.Get("Set-Cookie"))
+	assert.Equal(t, `{"object":"list","results":[]}`, string(query.Response.Body))
+
+	page := interactions[1]
+	assert.Empty(t, page.Request.Body)
+	assert.Equal(t, http.StatusBadGateway, page.Response.StatusCode)
+	assert.Equal(t, `<html>bad gateway for [REDACTED]</html>`, string(page.Response.Body))
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), FileName)
+	require.NoError(t, os.WriteFile(path, []byte("{\"request\": {\"method\": \"GET\"}}\n\nnot json\n"), 0600))
+
+	_, err := Load(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse line 3")
+}
@@ -1,18 +1,188 @@
 package config
 
-import "path/filepath"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// How a Notion property is written in the frontmatter.
+const (
+	// PropertyRename writes the property with another frontmatter key.
+	PropertyRename = "rename"
+	// PropertyTags adds the values of the property to the Obsidian tags.
+	PropertyTags = "tags"
+	// PropertyAliases adds the values of the property to the Obsidian aliases.
+	PropertyAliases = "aliases"
+	// PropertyDrop does not write the property.
+	PropertyDrop = "drop"
+)
+
+// How date ranges are written in the frontmatter.
+const (
+	// DateRangeInterval writes a range as an ISO 8601 interval: `2024-10-10/2024-10-12`.
+	DateRangeInterval = "interval"
+	// DateRangeSplit writes a range as two properties, `<key>_start` and `<key>_end`.
+	DateRangeSplit = "split"
+)
+
+// How toggles and toggleable headings are written.
+const (
+	// TogglesList writes a toggle as a list item and a toggleable heading as a heading, with their children indented.
+	TogglesList = "list"
+	// TogglesCallout writes toggles as folded Obsidian callouts, `> [!note]-`, with their children inside,
+	// and toggleable headings as headings with their children below them.
+	TogglesCallout = "callout"
+	// TogglesDetails writes toggles as HTML `<details>` elements, with their children inside,
+	// and toggleable headings as headings with their children below them.
+	TogglesDetails = "details"
+)
+
+// DefaultRecursiveDepth is how many levels of child pages are migrated with -recursive.
+const DefaultRecursiveDepth = 3
+
+// DefaultDateFormat is the strftime format of the dates in page names and date mentions.
+const DefaultDateFormat = "%Y-%m-%d"
+
+// DefaultMaxFilenameLength is the maximum length in bytes of the note and folder names built from Notion titles.
+// It leaves room for the suffix added to duplicated names within the 255 bytes most filesystems allow.
+const DefaultMaxFilenameLength = 200
+
+// DefaultFilenameReplacements replaces the characters not allowed in file names on Windows and synced vaults,
+// and the characters that break wikilinks. Slashes in titles would create folders.
+var DefaultFilenameReplacements = map[string]string{
+	"/":  "-",
+	"\\": "-",
+	":":  "-",
+	"|":  "-",
+	"*":  "",
+	"?":  "",
+	"\"": "",
+	"<":  "",
+	">":  "",
+	"#":  "",
+	"^":  "",
+	"[":  "(",
+	"]":  ")",
+}
+
+// DefaultCalloutType is the Obsidian callout type of the Notion callouts whose icon and color are not mapped.
+const DefaultCalloutType = "note"
+
+// DefaultCalloutTypes maps the icon emoji and the color of Notion callouts to Obsidian callout types,
+// https://help.obsidian.md/Editing+and+formatting/Callouts. The emoji take precedence over the colors.
+var DefaultCalloutTypes = map[string]string{
+	"💡":      "tip",
+	"⚠️":     "warning",
+	"🚧":      "warning",
+	"❗":      "important",
+	"❓":      "question",
+	"ℹ️":     "info",
+	"📝":      "note",
+	"✅":      "success",
+	"❌":      "failure",
+	"🚨":      "danger",
+	"🐛":      "bug",
+	"💬":      "quote",
+	"red":    "danger",
+	"orange": "warning",
+	"yellow": "warning",
+	"green":  "success",
+	"blue":   "info",
+	"purple": "example",
+	"gray":   "note",
+}
+
+var calloutType = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// What to do with the notes of Notion pages removed since the last sync.
+const (
+	SyncRemovedKeep    = "keep"
+	SyncRemovedDelete  = "delete"
+	SyncRemovedArchive = "archive"
+)
 
 type Config struct {
-	Token                   string
-	DatabaseID              string
-	PageID                  string
+	Token      string
+	DatabaseID string
+	PageID     string
+	// Workspace migrates every page and database shared with the Notion integration.
+	Workspace               bool
 	PagePropertiesToMigrate map[string]bool
-	VaultPath               string
-	VaultDestination        string
-	StoreImages             bool
-	PageNameFilters         map[string]string
-	SaveToDisk              bool
-	Debug                   bool
+	// PropertyMappings changes the frontmatter key of the Notion properties, keyed by lowercased property name.
+	PropertyMappings map[string]PropertyMapping
+	VaultPath        string
+	VaultDestination string
+	StoreImages      bool
+	PageNameFilters  map[string]string
+	// PageNameTemplate is a text/template building the page names from the page properties.
+	// It replaces the PageNameFilters.
+	PageNameTemplate string
+	// Filter and Sorts select and order the pages of the database, as Notion query JSON or as an expression
+	// like `Status = Done AND Date >= 2024-01-01` and a list like `Date desc, Name`.
+	Filter string
+	Sorts  string
+	// FilenameReplacements replaces text in the note and folder names. They are added to DefaultFilenameReplacements,
+	// a replacement of a default character overrides it.
+	FilenameReplacements map[string]string
+	// CalloutTypes maps the icon emoji and the colors of Notion callouts to Obsidian callout types.
+	// They are added to DefaultCalloutTypes, a mapping of a default emoji or color overrides it.
+	CalloutTypes map[string]string
+	// Toggles is how toggles and toggleable headings are written, TogglesList, TogglesCallout or TogglesDetails.
+	// Empty means TogglesList.
+	Toggles string
+	// MaxFilenameLength caps the note and folder names, in bytes. Zero means DefaultMaxFilenameLength.
+	MaxFilenameLength int
+	// DateFormat is the strftime format of the dates in page names without a format, and in date mentions.
+	// Empty means DefaultDateFormat.
+	DateFormat string
+	// DateRange is how date ranges are written in the frontmatter, DateRangeInterval or DateRangeSplit.
+	DateRange string
+	// TimeZone is the time zone the dates with time are converted to. Nil keeps the time zone returned by Notion.
+	TimeZone   *time.Location
+	SaveToDisk bool
+	Debug      bool
+	// Recursive migrates the child pages and child databases found in the pages.
+	Recursive bool
+	// RecursiveDepth limits how many levels of child pages are migrated. Zero means no limit.
+	RecursiveDepth int
+	// RequestsPerSecond, RateLimitBurst and MaxRetries configure the requests to the Notion API.
+	// Zero requests per second disables the rate limit.
+	RequestsPerSecond float64
+	RateLimitBurst    int
+	MaxRetries        int
+	// Sync only migrates the pages edited since the last run, using the state stored in the vault.
+	Sync bool
+	// SyncRemoved is what to do with the notes of removed Notion pages: keep, delete or archive them.
+	SyncRemoved string
+	// Cache stores the Notion API responses on disk, in CacheDir, for CacheTTL.
+	Cache    bool
+	CacheDir string
+	CacheTTL time.Duration
+	// Offline renders the pages from the cached Notion API responses without requesting Notion.
+	Offline bool
+	// NotionBaseURL replaces the Notion API URL, for example to use a local stand-in of the Notion API.
+	NotionBaseURL string
+	// Proxy, CABundle, RequestTimeout and UserAgent configure the requests to Notion and the image downloads.
+	Proxy          string
+	CABundle       string
+	RequestTimeout time.Duration
+	UserAgent      string
+}
+
+// PropertyMapping is a rule for writing a Notion property in the frontmatter.
+// Properties mapped to the same key are merged in a list.
+type PropertyMapping struct {
+	Action string
+	// Key is the frontmatter key of renamed properties.
+	Key string
+	// TagPrefix is prepended to the tags, for example `area/`.
+	TagPrefix string
 }
 
 func (c *Config) VaultFilepath() string {
@@ -22,3 +192,260 @@ func (c *Config) VaultFilepath() string {
 func (c *Config) VaultImagePath() string {
 	return filepath.Join(c.VaultPath, "Images")
 }
+
+// ValidateSyncRemoved checks the action for the notes of removed Notion pages.
+func ValidateSyncRemoved(action string) error {
+	switch action {
+	case "", SyncRemovedKeep, SyncRemovedDelete, SyncRemovedArchive:
+		return nil
+	default:
+		return fmt.Errorf("unsupported action %q for removed pages. use keep, delete or archive", action)
+	}
+}
+
+// ValidateDateRange checks how date ranges are written.
+func ValidateDateRange(value string) error {
+	switch value {
+	case "", DateRangeInterval, DateRangeSplit:
+		return nil
+	default:
+		return fmt.Errorf("unsupported date range %q. use interval or split", value)
+	}
+}
+
+// ValidateToggles checks how toggles are written.
+func ValidateToggles(value string) error {
+	switch value {
+	case "", TogglesList, TogglesCallout, TogglesDetails:
+		return nil
+	default:
+		return fmt.Errorf("unsupported toggles %q. use list, callout or details", value)
+	}
+}
+
+// LoadTimeZone returns the IANA time zone with the given name, for example `Europe/Madrid`.
+// An empty name returns nil, the time zone returned by Notion is kept.
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return nil, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q. use an IANA time zone like Europe/Madrid", name)
+	}
+
+	return location, nil
+}
+
+// ValidateURL checks an absolute http or https URL. An empty URL is valid, the default is used.
+func ValidateURL(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q in %s. use http or https", parsed.Scheme, value)
+	}
+
+	if parsed.Host == "" {
+		return fmt.Errorf("missing host in %s", value)
+	}
+
+	return nil
+}
+
+// ParsePageProperties parses a comma-separated list of Notion page properties.
+// Property names are lowercased to match them regardless of their case in Notion.
+func ParsePageProperties(list string) map[string]bool {
+	pageProperties := map[string]bool{}
+
+	if list == "" {
+		return pageProperties
+	}
+
+	for _, prop := range strings.Split(list, ",") {
+		pageProperties[strings.ToLower(strings.TrimSpace(prop))] = true
+	}
+
+	return pageProperties
+}
+
+// ParsePropertyMappings parses a comma-separated list of property mappings,
+// for example `Status=state,Category=tags:area/,Nickname=aliases,Internal=-`.
+func ParsePropertyMappings(list string) (map[string]PropertyMapping, error) {
+	mappings := map[string]PropertyMapping{}
+
+	if list == "" {
+		return mappings, nil
+	}
+
+	for _, rule := range strings.Split(list, ",") {
+		property, target, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid property mapping %q. use <property>=<key>", rule)
+		}
+
+		if err := AddPropertyMapping(mappings, property, target); err != nil {
+			return nil, err
+		}
+	}
+
+	return mappings, nil
+}
+
+// AddPropertyMapping parses the target of a Notion property:
+// a frontmatter key, `tags` with an optional prefix like `tags:area/`, `aliases` or `-` to drop it.
+func AddPropertyMapping(mappings map[string]PropertyMapping, property, target string) error {
+	property = strings.ToLower(strings.TrimSpace(property))
+	target = strings.TrimSpace(target)
+
+	if property == "" {
+		return fmt.Errorf("missing property name in property mapping %q", target)
+	}
+
+	if _, ok := mappings[property]; ok {
+		return fmt.Errorf("duplicated property mapping for %s", property)
+	}
+
+	var mapping PropertyMapping
+
+	switch name, prefix, _ := strings.Cut(target, ":"); name {
+	case "":
+		return fmt.Errorf("missing frontmatter key in property mapping for %s", property)
+	case "-":
+		mapping = PropertyMapping{Action: PropertyDrop}
+	case PropertyTags:
+		mapping = PropertyMapping{Action: PropertyTags, Key: PropertyTags, TagPrefix: prefix}
+	case PropertyAliases:
+		mapping = PropertyMapping{Action: PropertyAliases, Key: PropertyAliases}
+	default:
+		mapping = PropertyMapping{Action: PropertyRename, Key: target}
+	}
+
+	mappings[property] = mapping
+
+	return nil
+}
+
+// ParseFilenameReplacements parses a comma-separated list of replacements for the note names,
+// for example `:= -,&=and`. An empty replacement removes the text.
+func ParseFilenameReplacements(list string) (map[string]string, error) {
+	replacements := map[string]string{}
+
+	if list == "" {
+		return replacements, nil
+	}
+
+	for _, rule := range strings.Split(list, ",") {
+		from, to, ok := strings.Cut(rule, "=")
+		if !ok || from == "" {
+			return nil, fmt.Errorf("invalid filename replacement %q. use <text>=<replacement>", rule)
+		}
+
+		replacements[from] = to
+	}
+
+	return replacements, ValidateFilenameReplacements(replacements)
+}
+
+// ValidateFilenameReplacements checks the replacements do not add folders to the note names.
+func ValidateFilenameReplacements(replacements map[string]string) error {
+	for from, to := range replacements {
+		if from == "" {
+			return errors.New("missing text to replace")
+		}
+
+		if strings.ContainsAny(to, "/\\") {
+			return fmt.Errorf("the replacement of %q can not contain slashes, they would create folders", from)
+		}
+	}
+
+	return nil
+}
+
+// ParseCalloutTypes parses a comma-separated list of callout icons or colors and their Obsidian callout type,
+// for example `🔥=danger,purple=abstract`.
+func ParseCalloutTypes(list string) (map[string]string, error) {
+	types := map[string]string{}
+
+	if list == "" {
+		return types, nil
+	}
+
+	for _, rule := range strings.Split(list, ",") {
+		from, to, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid callout type %q. use <emoji or color>=<callout type>", rule)
+		}
+
+		types[strings.TrimSpace(from)] = strings.TrimSpace(to)
+	}
+
+	return types, ValidateCalloutTypes(types)
+}
+
+// ValidateCalloutTypes checks the callout types can be written in an Obsidian callout, `> [!type]`.
+func ValidateCalloutTypes(types map[string]string) error {
+	for from, to := range types {
+		if from == "" {
+			return errors.New("missing callout emoji or color")
+		}
+
+		if !calloutType.MatchString(to) {
+			return fmt.Errorf("invalid callout type %q for %s. use letters, digits, dashes and underscores", to, from)
+		}
+	}
+
+	return nil
+}
+
+// PageNameTemplateFuncs are the names of the functions available in the page name templates.
+var PageNameTemplateFuncs = []string{"date", "join", "lower", "slug", "truncate", "upper"}
+
+// ValidatePageNameTemplate checks a page name template can be parsed and only uses the available functions.
+func ValidatePageNameTemplate(pageName string) error {
+	// Parsing only checks the functions exist, they are never called.
+	funcs := template.FuncMap{}
+	for _, name := range PageNameTemplateFuncs {
+		funcs[name] = func() string { return "" }
+	}
+
+	if _, err := template.New("page-name").Funcs(funcs).Parse(pageName); err != nil {
+		return fmt.Errorf("invalid page name template %s. error: %w", pageName, err)
+	}
+
+	return nil
+}
+
+// IsPageNameTemplate reports if a page name is a text/template, for example `{{.Status}}/{{.Name}}`,
+// instead of a list of page properties.
+func IsPageNameTemplate(pageName string) bool {
+	return strings.Contains(pageName, "{{")
+}
+
+// ParsePageNameFilters parses a comma-separated list of Notion page properties
+// with an optional format, for example `date:%Y/%B/%d-%A,title`.
+func ParsePageNameFilters(list string) map[string]string {
+	pageNameFilters := map[string]string{}
+
+	if list == "" {
+		return pageNameFilters
+	}
+
+	for _, pagePathAttribute := range strings.Split(list, ",") {
+		pageWithFormatOptions := strings.Split(pagePathAttribute, ":")
+		if len(pageWithFormatOptions) > 1 {
+			pageNameFilters[strings.ToLower(pageWithFormatOptions[0])] = pageWithFormatOptions[1]
+		} else {
+			pageNameFilters[strings.ToLower(pageWithFormatOptions[0])] = ""
+		}
+	}
+
+	return pageNameFilters
+}
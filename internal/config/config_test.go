@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVaultFilepath(t *testing.T) {
@@ -23,3 +24,73 @@ func TestVaultImagePath(t *testing.T) {
 
 	assert.Equal(t, "test/Images", c.VaultImagePath())
 }
+
+func TestParsePropertyMappings(t *testing.T) {
+	mappings, err := ParsePropertyMappings("Status=state, Category=tags:area/,Labels=tags,Nickname=aliases,Internal=-")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]PropertyMapping{
+		"status":   {Action: PropertyRename, Key: "state"},
+		"category": {Action: PropertyTags, Key: "tags", TagPrefix: "area/"},
+		"labels":   {Action: PropertyTags, Key: "tags"},
+		"nickname": {Action: PropertyAliases, Key: "aliases"},
+		"internal": {Action: PropertyDrop},
+	}, mappings)
+
+	mappings, err = ParsePropertyMappings("")
+	require.NoError(t, err)
+	assert.Empty(t, mappings)
+
+	for list, expected := range map[string]string{
+		"Status":                "invalid property mapping \"Status\"",
+		"=state":                "missing property name",
+		"Status=":               "missing frontmatter key in property mapping for status",
+		"Status=a,status=b":     "duplicated property mapping for status",
+		"Category=:prefix/,A=b": "missing frontmatter key in property mapping for category",
+	} {
+		_, err = ParsePropertyMappings(list)
+		require.Error(t, err, list)
+		assert.Contains(t, err.Error(), expected)
+	}
+}
+
+func TestParseFilenameReplacements(t *testing.T) {
+	replacements, err := ParseFilenameReplacements(":= -,&=and,?=")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{":": " -", "&": "and", "?": ""}, replacements)
+
+	replacements, err = ParseFilenameReplacements("")
+	require.NoError(t, err)
+	assert.Empty(t, replacements)
+
+	for list, expected := range map[string]string{
+		":":     "invalid filename replacement \":\"",
+		"=-":    "invalid filename replacement \"=-\"",
+		":=a/b": "the replacement of \":\" can not contain slashes",
+		"|=\\":  "the replacement of \"|\" can not contain slashes",
+	} {
+		_, err = ParseFilenameReplacements(list)
+		require.Error(t, err, list)
+		assert.Contains(t, err.Error(), expected)
+	}
+}
+
+func TestParseCalloutTypes(t *testing.T) {
+	types, err := ParseCalloutTypes("🔥=danger, purple = abstract")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"🔥": "danger", "purple": "abstract"}, types)
+
+	types, err = ParseCalloutTypes("")
+	require.NoError(t, err)
+	assert.Empty(t, types)
+
+	for list, expected := range map[string]string{
+		"🔥":             "invalid callout type \"🔥\"",
+		"=tip":          "missing callout emoji or color",
+		"🔥=":            "invalid callout type \"\" for 🔥",
+		"red=[!danger]": "invalid callout type \"[!danger]\" for red",
+	} {
+		_, err = ParseCalloutTypes(list)
+		require.Error(t, err, list)
+		assert.Contains(t, err.Error(), expected)
+	}
+}
@@ -0,0 +1,378 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/GustavoCaso/n2o/internal/dbquery"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a declarative migration job. It lists every Notion database and page
+// to migrate in a single run, with settings shared by all of them.
+// The settings where zero has a meaning, like no limit, are pointers: nil means the setting is not in the file.
+type File struct {
+	Token          string `yaml:"notion-token"    toml:"notion-token"`
+	VaultPath      string `yaml:"vault-path"      toml:"vault-path"`
+	DownloadImages bool   `yaml:"download-images" toml:"download-images"`
+	SaveToDisk     bool   `yaml:"save-to-disk"    toml:"save-to-disk"`
+	Debug          bool   `yaml:"debug"           toml:"debug"`
+	Recursive      bool   `yaml:"recursive"       toml:"recursive"`
+	RecursiveDepth *int   `yaml:"recursive-depth" toml:"recursive-depth"`
+	// The Notion API limits are shared by every source.
+	RequestsPerSecond *float64  `yaml:"requests-per-second" toml:"requests-per-second"`
+	RateLimitBurst    *int      `yaml:"rate-limit-burst"    toml:"rate-limit-burst"`
+	MaxRetries        *int      `yaml:"max-retries"         toml:"max-retries"`
+	Sync              bool      `yaml:"sync"                toml:"sync"`
+	SyncRemoved       string    `yaml:"sync-removed"        toml:"sync-removed"`
+	Cache             bool      `yaml:"cache"               toml:"cache"`
+	CacheDir          string    `yaml:"cache-dir"           toml:"cache-dir"`
+	CacheTTL          *Duration `yaml:"cache-ttl"           toml:"cache-ttl"`
+	Offline           bool      `yaml:"offline"             toml:"offline"`
+	// The HTTP settings apply to the requests to Notion and the image downloads.
+	NotionBaseURL  string    `yaml:"notion-base-url" toml:"notion-base-url"`
+	Proxy          string    `yaml:"proxy"           toml:"proxy"`
+	CABundle       string    `yaml:"ca-bundle"       toml:"ca-bundle"`
+	RequestTimeout *Duration `yaml:"request-timeout" toml:"request-timeout"`
+	UserAgent      string    `yaml:"user-agent"      toml:"user-agent"`
+	// The date settings apply to the page names, the frontmatter and the date mentions.
+	DateFormat string `yaml:"date-format" toml:"date-format"`
+	DateRange  string `yaml:"date-range"  toml:"date-range"`
+	TimeZone   string `yaml:"time-zone"   toml:"time-zone"`
+	// The file name settings apply to every note and folder named after a Notion title.
+	FilenameReplace   map[string]string `yaml:"filename-replace"    toml:"filename-replace"`
+	MaxFilenameLength int               `yaml:"max-filename-length" toml:"max-filename-length"`
+	// CalloutMap maps the icon emoji and the colors of Notion callouts to Obsidian callout types.
+	CalloutMap map[string]string `yaml:"callout-map" toml:"callout-map"`
+	Toggles    string            `yaml:"toggles"     toml:"toggles"`
+	Sources    []Source          `yaml:"sources"     toml:"sources"`
+}
+
+// Source is a single Notion database or page to migrate.
+// Every source carries its own properties, page name format and destination folder.
+// The page name is a list of page properties, or a text/template.
+type Source struct {
+	Name           string   `yaml:"name"            toml:"name"`
+	DatabaseID     string   `yaml:"database-id"     toml:"database-id"`
+	PageID         string   `yaml:"page-id"         toml:"page-id"`
+	Workspace      bool     `yaml:"workspace"       toml:"workspace"`
+	PageProperties []string `yaml:"page-properties" toml:"page-properties"`
+	PageName       string   `yaml:"page-name"       toml:"page-name"`
+	VaultFolder    string   `yaml:"vault-folder"    toml:"vault-folder"`
+	// Filter and Sort select and order the pages of a database.
+	Filter string `yaml:"filter" toml:"filter"`
+	Sort   string `yaml:"sort"   toml:"sort"`
+	// PropertyMap maps Notion properties to a frontmatter key, `tags`, `tags:<prefix>`, `aliases` or `-` to drop them.
+	PropertyMap map[string]string `yaml:"property-map" toml:"property-map"`
+	// DownloadImages overrides the top level setting when present.
+	DownloadImages *bool `yaml:"download-images" toml:"download-images"`
+}
+
+// LoadFile reads a migration job from a YAML (.yaml, .yml) or TOML (.toml) file.
+func LoadFile(path string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file %s. error: %w", path, err)
+	}
+
+	file := &File{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(file); err != nil {
+			return nil, fmt.Errorf("failed to parse YAML config file %s. error: %w", path, err)
+		}
+	case ".toml":
+		metadata, decodeErr := toml.Decode(string(content), file)
+		if decodeErr != nil {
+			return nil, fmt.Errorf("failed to parse TOML config file %s. error: %w", path, decodeErr)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("unknown fields in TOML config file %s: %v", path, undecoded)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file extension %q. use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+
+	return file, nil
+}
+
+// Validate reports every invalid setting in the file.
+// Errors for a source point at its position in the sources list.
+func (f *File) Validate() error {
+	var errs []error
+
+	if f.Token == "" {
+		errs = append(errs, errors.New("notion-token: you must provide the notion token"))
+	}
+
+	if f.VaultPath == "" {
+		errs = append(errs, errors.New("vault-path: you must provide the Obsidian vault path"))
+	}
+
+	if valueOf(f.RecursiveDepth) < 0 {
+		errs = append(errs, errors.New("recursive-depth: must be zero or a positive number"))
+	}
+
+	if valueOf(f.RequestsPerSecond) < 0 {
+		errs = append(errs, errors.New("requests-per-second: must be zero or a positive number"))
+	}
+
+	if f.RateLimitBurst != nil && *f.RateLimitBurst < 1 {
+		errs = append(errs, errors.New("rate-limit-burst: must be a positive number"))
+	}
+
+	if valueOf(f.MaxRetries) < 0 {
+		errs = append(errs, errors.New("max-retries: must be zero or a positive number"))
+	}
+
+	if valueOf(f.CacheTTL) < 0 {
+		errs = append(errs, errors.New("cache-ttl: must be zero or a positive duration"))
+	}
+
+	if valueOf(f.RequestTimeout) < 0 {
+		errs = append(errs, errors.New("request-timeout: must be zero or a positive duration"))
+	}
+
+	if err := ValidateURL(f.NotionBaseURL); err != nil {
+		errs = append(errs, fmt.Errorf("notion-base-url: %w", err))
+	}
+
+	if err := ValidateURL(f.Proxy); err != nil {
+		errs = append(errs, fmt.Errorf("proxy: %w", err))
+	}
+
+	if err := ValidateSyncRemoved(f.SyncRemoved); err != nil {
+		errs = append(errs, fmt.Errorf("sync-removed: %w", err))
+	}
+
+	if err := ValidateDateRange(f.DateRange); err != nil {
+		errs = append(errs, fmt.Errorf("date-range: %w", err))
+	}
+
+	if _, err := LoadTimeZone(f.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("time-zone: %w", err))
+	}
+
+	if err := ValidateFilenameReplacements(f.FilenameReplace); err != nil {
+		errs = append(errs, fmt.Errorf("filename-replace: %w", err))
+	}
+
+	if f.MaxFilenameLength < 0 {
+		errs = append(errs, errors.New("max-filename-length: must be zero or a positive number"))
+	}
+
+	if err := ValidateCalloutTypes(f.CalloutMap); err != nil {
+		errs = append(errs, fmt.Errorf("callout-map: %w", err))
+	}
+
+	if err := ValidateToggles(f.Toggles); err != nil {
+		errs = append(errs, fmt.Errorf("toggles: %w", err))
+	}
+
+	if len(f.Sources) == 0 {
+		errs = append(errs, errors.New("sources: you must provide at least one database or page to migrate"))
+	}
+
+	seen := map[string]int{}
+
+	for i, source := range f.Sources {
+		if err := source.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", source.label(i), err))
+			continue
+		}
+
+		id := source.DatabaseID + source.PageID
+		if source.Workspace {
+			id = "the workspace"
+		}
+		if previous, ok := seen[id]; ok {
+			errs = append(errs, fmt.Errorf(
+				"%s: duplicated source, %s is already migrated by %s",
+				source.label(i),
+				id,
+				f.Sources[previous].label(previous),
+			))
+			continue
+		}
+		seen[id] = i
+	}
+
+	return errors.Join(errs...)
+}
+
+// Configs builds one Config per source, inheriting the settings shared by the whole file.
+func (f *File) Configs() []*Config {
+	configs := make([]*Config, len(f.Sources))
+
+	// The time zone is checked by Validate.
+	timeZone, _ := LoadTimeZone(f.TimeZone)
+
+	recursiveDepth := DefaultRecursiveDepth
+	if f.RecursiveDepth != nil {
+		recursiveDepth = *f.RecursiveDepth
+	}
+
+	for i, source := range f.Sources {
+		storeImages := f.DownloadImages
+		if source.DownloadImages != nil {
+			storeImages = *source.DownloadImages
+		}
+
+		// The mappings are checked by Validate.
+		propertyMappings, _ := source.propertyMappings()
+
+		pageNameFilters := map[string]string{}
+		pageNameTemplate := ""
+		if IsPageNameTemplate(source.PageName) {
+			pageNameTemplate = source.PageName
+		} else {
+			pageNameFilters = ParsePageNameFilters(source.PageName)
+		}
+
+		configs[i] = &Config{
+			Token:                   f.Token,
+			DatabaseID:              source.DatabaseID,
+			PageID:                  source.PageID,
+			Workspace:               source.Workspace,
+			PagePropertiesToMigrate: ParsePageProperties(strings.Join(source.PageProperties, ",")),
+			PropertyMappings:        propertyMappings,
+			VaultPath:               f.VaultPath,
+			VaultDestination:        source.VaultFolder,
+			StoreImages:             storeImages,
+			PageNameFilters:         pageNameFilters,
+			PageNameTemplate:        pageNameTemplate,
+			Filter:                  source.Filter,
+			Sorts:                   source.Sort,
+			FilenameReplacements:    f.FilenameReplace,
+			MaxFilenameLength:       f.MaxFilenameLength,
+			CalloutTypes:            f.CalloutMap,
+			Toggles:                 f.Toggles,
+			DateFormat:              f.DateFormat,
+			DateRange:               f.DateRange,
+			TimeZone:                timeZone,
+			SaveToDisk:              f.SaveToDisk,
+			Debug:                   f.Debug,
+			Recursive:               f.Recursive,
+			RecursiveDepth:          recursiveDepth,
+			RequestsPerSecond:       valueOf(f.RequestsPerSecond),
+			RateLimitBurst:          valueOf(f.RateLimitBurst),
+			MaxRetries:              valueOf(f.MaxRetries),
+			Sync:                    f.Sync,
+			SyncRemoved:             f.SyncRemoved,
+			Cache:                   f.Cache,
+			CacheDir:                f.CacheDir,
+			CacheTTL:                time.Duration(valueOf(f.CacheTTL)),
+			Offline:                 f.Offline,
+			NotionBaseURL:           f.NotionBaseURL,
+			Proxy:                   f.Proxy,
+			CABundle:                f.CABundle,
+			RequestTimeout:          time.Duration(valueOf(f.RequestTimeout)),
+			UserAgent:               f.UserAgent,
+		}
+	}
+
+	return configs
+}
+
+// valueOf returns the value of a setting, zero when it is not set.
+func valueOf[T any](value *T) T {
+	if value == nil {
+		var zero T
+		return zero
+	}
+
+	return *value
+}
+
+// Duration is a time.Duration written as a string in the config file, for example `12h` or `30m`.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(duration)
+
+	return nil
+}
+
+func (s Source) validate() error {
+	if s.Workspace && (s.DatabaseID != "" || s.PageID != "") {
+		return errors.New("you must provide a database-id, a page-id or workspace not more than one")
+	}
+
+	if s.DatabaseID == "" && s.PageID == "" && !s.Workspace {
+		return errors.New("you must provide a database-id or a page-id")
+	}
+
+	if s.DatabaseID != "" && s.PageID != "" {
+		return errors.New("you must provide a database-id or a page-id not both")
+	}
+
+	if s.DatabaseID == "" && (s.Filter != "" || s.Sort != "") {
+		return errors.New("filter and sort can only be used with a database-id")
+	}
+
+	if _, err := dbquery.ParseFilter(s.Filter); err != nil {
+		return fmt.Errorf("filter: %w", err)
+	}
+
+	if _, err := dbquery.ParseSorts(s.Sort); err != nil {
+		return fmt.Errorf("sort: %w", err)
+	}
+
+	if filepath.IsAbs(s.VaultFolder) || strings.HasPrefix(filepath.Clean(s.VaultFolder), "..") {
+		return fmt.Errorf("vault-folder %q must be a relative path inside the Obsidian vault", s.VaultFolder)
+	}
+
+	if IsPageNameTemplate(s.PageName) {
+		if err := ValidatePageNameTemplate(s.PageName); err != nil {
+			return fmt.Errorf("page-name: %w", err)
+		}
+	}
+
+	if _, err := s.propertyMappings(); err != nil {
+		return fmt.Errorf("property-map: %w", err)
+	}
+
+	return nil
+}
+
+func (s Source) propertyMappings() (map[string]PropertyMapping, error) {
+	mappings := map[string]PropertyMapping{}
+
+	properties := make([]string, 0, len(s.PropertyMap))
+	for property := range s.PropertyMap {
+		properties = append(properties, property)
+	}
+	// Sorted, so the same property written with different cases is always reported the same way.
+	sort.Strings(properties)
+
+	for _, property := range properties {
+		if err := AddPropertyMapping(mappings, property, s.PropertyMap[property]); err != nil {
+			return nil, err
+		}
+	}
+
+	return mappings, nil
+}
+
+func (s Source) label(index int) string {
+	if s.Name != "" {
+		return fmt.Sprintf("sources[%d] (%s)", index, s.Name)
+	}
+
+	return fmt.Sprintf("sources[%d]", index)
+}
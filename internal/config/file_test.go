@@ -0,0 +1,249 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const yamlConfig = `
+notion-token: secret
+vault-path: /vault
+download-images: true
+save-to-disk: true
+requests-per-second: 2.5
+rate-limit-burst: 5
+max-retries: 10
+cache: true
+cache-ttl: 12h
+proxy: http://proxy.example.com:3128
+request-timeout: 90s
+user-agent: n2o-corp
+date-format: "%d/%m/%Y"
+date-range: split
+time-zone: Europe/Madrid
+filename-replace:
+  ":": " -"
+max-filename-length: 120
+callout-map:
+  "🔥": danger
+toggles: callout
+sources:
+  - name: meetings
+    database-id: "000000"
+    page-properties: [Date, Attendees]
+    page-name: "date:%Y/%m/%d"
+    vault-folder: Meetings
+    filter: Type = Weekly AND Date >= 2024-01-01
+    sort: Date desc
+    property-map:
+      Type: tags:meeting/
+      Notes: "-"
+  - page-id: "111111"
+    page-name: "{{.Title | slug}}"
+    vault-folder: Notes
+    download-images: false
+`
+
+const tomlConfig = `
+notion-token = "secret"
+vault-path = "/vault"
+cache-ttl = "30m"
+notion-base-url = "http://localhost:8080/v1"
+ca-bundle = "/etc/ssl/corp.pem"
+
+[[sources]]
+database-id = "000000"
+page-properties = ["all"]
+page-name = "title"
+vault-folder = "Projects"
+`
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		file, err := LoadFile(writeConfigFile(t, "n2o.yaml", yamlConfig))
+		require.NoError(t, err)
+		require.NoError(t, file.Validate())
+
+		configs := file.Configs()
+		require.Len(t, configs, 2)
+
+		assert.Equal(t, "secret", configs[0].Token)
+		assert.Equal(t, "000000", configs[0].DatabaseID)
+		assert.Equal(t, map[string]bool{"date": true, "attendees": true}, configs[0].PagePropertiesToMigrate)
+		assert.Equal(t, map[string]string{"date": "%Y/%m/%d"}, configs[0].PageNameFilters)
+		assert.Empty(t, configs[0].PageNameTemplate)
+		assert.Equal(t, "Type = Weekly AND Date >= 2024-01-01", configs[0].Filter)
+		assert.Equal(t, "Date desc", configs[0].Sorts)
+		assert.Equal(t, "/vault/Meetings", configs[0].VaultFilepath())
+		assert.Equal(t, map[string]PropertyMapping{
+			"type":  {Action: PropertyTags, Key: "tags", TagPrefix: "meeting/"},
+			"notes": {Action: PropertyDrop},
+		}, configs[0].PropertyMappings)
+		assert.Empty(t, configs[1].PropertyMappings)
+		assert.True(t, configs[0].StoreImages)
+		assert.True(t, configs[0].SaveToDisk)
+		assert.Equal(t, 2.5, configs[0].RequestsPerSecond)
+		assert.Equal(t, 5, configs[0].RateLimitBurst)
+		assert.Equal(t, 10, configs[0].MaxRetries)
+		assert.True(t, configs[0].Cache)
+		assert.Equal(t, 12*time.Hour, configs[0].CacheTTL)
+		assert.Equal(t, "http://proxy.example.com:3128", configs[0].Proxy)
+		assert.Equal(t, 90*time.Second, configs[0].RequestTimeout)
+		assert.Equal(t, "n2o-corp", configs[0].UserAgent)
+		assert.Equal(t, "%d/%m/%Y", configs[0].DateFormat)
+		assert.Equal(t, map[string]string{":": " -"}, configs[1].FilenameReplacements)
+		assert.Equal(t, 120, configs[1].MaxFilenameLength)
+		assert.Equal(t, map[string]string{"🔥": "danger"}, configs[1].CalloutTypes)
+		assert.Equal(t, TogglesCallout, configs[1].Toggles)
+		assert.Equal(t, DateRangeSplit, configs[1].DateRange)
+		require.NotNil(t, configs[1].TimeZone)
+		assert.Equal(t, "Europe/Madrid", configs[1].TimeZone.String())
+		assert.Equal(t, 2.5, configs[1].RequestsPerSecond)
+
+		assert.Equal(t, "111111", configs[1].PageID)
+		assert.Equal(t, "{{.Title | slug}}", configs[1].PageNameTemplate)
+		assert.Empty(t, configs[1].PageNameFilters)
+		assert.Equal(t, "/vault/Notes", configs[1].VaultFilepath())
+		assert.False(t, configs[1].StoreImages)
+		assert.Empty(t, configs[1].PagePropertiesToMigrate)
+	})
+
+	t.Run("toml", func(t *testing.T) {
+		file, err := LoadFile(writeConfigFile(t, "n2o.toml", tomlConfig))
+		require.NoError(t, err)
+		require.NoError(t, file.Validate())
+
+		configs := file.Configs()
+		require.Len(t, configs, 1)
+		assert.Equal(t, map[string]bool{"all": true}, configs[0].PagePropertiesToMigrate)
+		assert.Equal(t, map[string]string{"title": ""}, configs[0].PageNameFilters)
+		assert.Equal(t, "/vault/Projects", configs[0].VaultFilepath())
+		assert.Equal(t, 30*time.Minute, configs[0].CacheTTL)
+		assert.Equal(t, "http://localhost:8080/v1", configs[0].NotionBaseURL)
+		assert.Equal(t, "/etc/ssl/corp.pem", configs[0].CABundle)
+	})
+
+	t.Run("zero settings", func(t *testing.T) {
+		file, err := LoadFile(writeConfigFile(t, "n2o.yaml", "requests-per-second: 0\nmax-retries: 0\ncache-ttl: 0s\nrequest-timeout: 0s\n"))
+		require.NoError(t, err)
+
+		require.NotNil(t, file.RequestsPerSecond)
+		assert.Equal(t, 0.0, *file.RequestsPerSecond)
+		require.NotNil(t, file.MaxRetries)
+		assert.Equal(t, 0, *file.MaxRetries)
+		require.NotNil(t, file.CacheTTL)
+		assert.Equal(t, Duration(0), *file.CacheTTL)
+		require.NotNil(t, file.RequestTimeout)
+		assert.Equal(t, Duration(0), *file.RequestTimeout)
+
+		file, err = LoadFile(writeConfigFile(t, "n2o.yaml", "sources: []\n"))
+		require.NoError(t, err)
+
+		assert.Nil(t, file.RequestsPerSecond)
+		assert.Nil(t, file.RateLimitBurst)
+		assert.Nil(t, file.MaxRetries)
+		assert.Nil(t, file.CacheTTL)
+		assert.Nil(t, file.RequestTimeout)
+	})
+
+	t.Run("recursive without depth", func(t *testing.T) {
+		file, err := LoadFile(writeConfigFile(t, "n2o.yaml", "recursive: true\nsources:\n  - page-id: \"111111\"\n"))
+		require.NoError(t, err)
+
+		configs := file.Configs()
+		require.Len(t, configs, 1)
+		assert.True(t, configs[0].Recursive)
+		assert.Equal(t, DefaultRecursiveDepth, configs[0].RecursiveDepth)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := LoadFile(writeConfigFile(t, "n2o.yaml", "sources:\n  - databse-id: \"000000\"\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "databse-id")
+	})
+
+	t.Run("unsupported extension", func(t *testing.T) {
+		_, err := LoadFile(writeConfigFile(t, "n2o.json", "{}"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported config file extension")
+	})
+}
+
+func TestFileValidate(t *testing.T) {
+	maxRetries := -1
+	burst := 0
+	file := &File{
+		Token:             "secret",
+		VaultPath:         "/vault",
+		RateLimitBurst:    &burst,
+		MaxRetries:        &maxRetries,
+		SyncRemoved:       "trash",
+		Proxy:             "proxy.example.com:3128",
+		DateRange:         "both",
+		TimeZone:          "Mars/Olympus",
+		FilenameReplace:   map[string]string{":": "/"},
+		MaxFilenameLength: -1,
+		CalloutMap:        map[string]string{"red": "red alert"},
+		Toggles:           "folded",
+		Sources: []Source{
+			{DatabaseID: "000000"},
+			{Name: "both", DatabaseID: "111111", PageID: "222222"},
+			{},
+			{DatabaseID: "000000"},
+			{PageID: "333333", VaultFolder: "../outside"},
+			{PageID: "555555", PropertyMap: map[string]string{"Status": "state", "status": "other"}},
+			{Workspace: true, PageID: "444444"},
+			{Workspace: true},
+			{PageID: "666666", Filter: "Status = Done"},
+			{Name: "template", PageID: "777777", PageName: "{{.Name | shout}}"},
+			{Name: "tasks", DatabaseID: "888888", Filter: "Status ~ Done"},
+			{Name: "notes", DatabaseID: "999999", Sort: "Date desc,"},
+		},
+	}
+
+	err := file.Validate()
+	require.Error(t, err)
+
+	assert.NotContains(t, err.Error(), "sources[0]:")
+	assert.Contains(t, err.Error(), "sources[1] (both): you must provide a database-id or a page-id not both")
+	assert.Contains(t, err.Error(), "sources[2]: you must provide a database-id or a page-id")
+	assert.Contains(t, err.Error(), "sources[3]: duplicated source, 000000 is already migrated by sources[0]")
+	assert.Contains(t, err.Error(), "sources[4]: vault-folder \"../outside\" must be a relative path")
+	assert.Contains(t, err.Error(), "sources[5]: property-map: duplicated property mapping for status")
+	assert.Contains(t, err.Error(), "sources[6]: you must provide a database-id, a page-id or workspace not more than one")
+	assert.NotContains(t, err.Error(), "sources[7]")
+	assert.Contains(t, err.Error(), "sources[8]: filter and sort can only be used with a database-id")
+	assert.Contains(t, err.Error(), `sources[9] (template): page-name: invalid page name template {{.Name | shout}}`)
+	assert.Contains(t, err.Error(), `function "shout" not defined`)
+	assert.Contains(t, err.Error(), "sources[10] (tasks): filter: failed to parse the filter Status ~ Done")
+	assert.Contains(t, err.Error(), "unknown comparison ~")
+	assert.Contains(t, err.Error(), "sources[11] (notes): sort: missing property in the sorts Date desc,")
+	assert.Contains(t, err.Error(), "rate-limit-burst: must be a positive number")
+	assert.Contains(t, err.Error(), "max-retries: must be zero or a positive number")
+	assert.Contains(t, err.Error(), "sync-removed: unsupported action \"trash\"")
+	assert.Contains(t, err.Error(), "proxy: unsupported scheme")
+	assert.NotContains(t, err.Error(), "notion-base-url")
+	assert.Contains(t, err.Error(), "date-range: unsupported date range \"both\"")
+	assert.Contains(t, err.Error(), "time-zone: unknown time zone \"Mars/Olympus\"")
+	assert.Contains(t, err.Error(), "filename-replace: the replacement of \":\" can not contain slashes")
+	assert.Contains(t, err.Error(), "max-filename-length: must be zero or a positive number")
+	assert.Contains(t, err.Error(), "callout-map: invalid callout type \"red alert\" for red")
+	assert.Contains(t, err.Error(), "toggles: unsupported toggles \"folded\"")
+
+	err = (&File{}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "notion-token")
+	assert.Contains(t, err.Error(), "vault-path")
+	assert.Contains(t, err.Error(), "sources")
+}
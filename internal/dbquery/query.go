@@ -0,0 +1,762 @@
+package dbquery
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// The comparisons of the filter expressions.
+const (
+	comparisonEquals         = "="
+	comparisonNotEquals      = "!="
+	comparisonGreater        = ">"
+	comparisonGreaterOrEqual = ">="
+	comparisonLess           = "<"
+	comparisonLessOrEqual    = "<="
+	comparisonContains       = "contains"
+	comparisonNotContains    = "!contains"
+	comparisonStartsWith     = "starts_with"
+	comparisonEndsWith       = "ends_with"
+	comparisonEmpty          = "is empty"
+	comparisonNotEmpty       = "is not empty"
+)
+
+// Filter is the filter of a database, written as Notion filter JSON or as an expression.
+type Filter struct {
+	json       *notion.DatabaseQueryFilter
+	expression *filterExpression
+}
+
+// filterExpression is a condition on a property, or conditions joined with `AND` or `OR`.
+type filterExpression struct {
+	operator   string
+	operands   []*filterExpression
+	property   string
+	comparison string
+	value      string
+}
+
+// ParseFilter parses a Notion filter object, https://developers.notion.com/reference/post-database-query-filter
+// or an expression like `Status = Done AND (Date >= 2024-01-01 OR Priority = High)`.
+func ParseFilter(text string) (*Filter, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(text, "{") {
+		filter := &notion.DatabaseQueryFilter{}
+		if err := decodeQueryJSON(text, filter); err != nil {
+			return nil, fmt.Errorf("failed to parse the filter JSON. error: %w", err)
+		}
+		return &Filter{json: filter}, nil
+	}
+
+	parser := &filterParser{}
+	if err := parser.tokenize(text); err != nil {
+		return nil, fmt.Errorf("failed to parse the filter %s. error: %w", text, err)
+	}
+
+	expression, err := parser.parse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the filter %s. error: %w", text, err)
+	}
+
+	return &Filter{expression: expression}, nil
+}
+
+// ParseSorts parses a Notion sorts array, https://developers.notion.com/reference/post-database-query-sort,
+// or a comma-separated list of properties with an optional direction like `Date desc, Name`.
+func ParseSorts(text string) ([]notion.DatabaseQuerySort, error) {
+	text = strings.TrimSpace(text)
+	sorts := []notion.DatabaseQuerySort{}
+
+	if text == "" {
+		return sorts, nil
+	}
+
+	if strings.HasPrefix(text, "[") {
+		if err := decodeQueryJSON(text, &sorts); err != nil {
+			return nil, fmt.Errorf("failed to parse the sorts JSON. error: %w", err)
+		}
+		return sorts, nil
+	}
+
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		direction := notion.SortDirAsc
+
+		if index := strings.LastIndex(item, " "); index != -1 {
+			switch strings.ToLower(item[index+1:]) {
+			case "asc", "ascending":
+				item = strings.TrimSpace(item[:index])
+			case "desc", "descending":
+				item = strings.TrimSpace(item[:index])
+				direction = notion.SortDirDesc
+			}
+		}
+
+		item = strings.Trim(item, `"`)
+		if item == "" {
+			return nil, fmt.Errorf("missing property in the sorts %s", text)
+		}
+
+		sorts = append(sorts, notion.DatabaseQuerySort{Property: item, Direction: direction})
+	}
+
+	return sorts, nil
+}
+
+var dateOnly = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// decodeQueryJSON decodes Notion query JSON, rejecting unknown fields so typos fail early.
+// go-notion reads the dates of the filters as times, the dates without time are read as midnight UTC.
+func decodeQueryJSON(text string, value any) error {
+	var raw any
+	if err := json.Unmarshal([]byte(text), &raw); err != nil {
+		return err
+	}
+
+	normalized, err := json.Marshal(normalizeFilterDates(raw, false))
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(value)
+}
+
+func normalizeFilterDates(value any, inDate bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = normalizeFilterDates(item, inDate || key == "date" || key == "created_time" ||
+				key == "last_edited_time")
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = normalizeFilterDates(item, inDate)
+		}
+		return v
+	case string:
+		if inDate && dateOnly.MatchString(v) {
+			return v + "T00:00:00Z"
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// Build builds the query of a database, checking the filter and the sorts against its properties.
+// It returns nil when there is neither a filter nor sorts.
+func Build(
+	filter *Filter,
+	sorts []notion.DatabaseQuerySort,
+	properties notion.DatabaseProperties,
+) (*notion.DatabaseQuery, error) {
+	if filter == nil && len(sorts) == 0 {
+		return nil, nil
+	}
+
+	query := &notion.DatabaseQuery{}
+
+	if filter != nil {
+		var queryFilter notion.DatabaseQueryFilter
+		var err error
+
+		if filter.json != nil {
+			queryFilter = *filter.json
+			err = validateFilter(queryFilter, properties)
+		} else {
+			queryFilter, err = filter.expression.filter(properties)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter. error: %w", err)
+		}
+
+		query.Filter = &queryFilter
+	}
+
+	for _, sort := range sorts {
+		if sort.Timestamp == "" {
+			name, ok := propertyName(sort.Property, properties)
+			switch {
+			case ok:
+				sort.Property = name
+			case isTimestamp(sort.Property):
+				sort.Timestamp = notion.SortTimestamp(strings.ToLower(sort.Property))
+				sort.Property = ""
+			default:
+				return nil, fmt.Errorf("invalid sort. error: %w", unknownProperty(sort.Property, properties))
+			}
+		}
+
+		if sort.Direction != notion.SortDirAsc && sort.Direction != notion.SortDirDesc {
+			return nil, fmt.Errorf("invalid sort direction %q. use ascending or descending", sort.Direction)
+		}
+
+		query.Sorts = append(query.Sorts, sort)
+	}
+
+	return query, nil
+}
+
+// validateFilter checks the properties of a Notion filter exist and are filtered with the condition for their type.
+func validateFilter(filter notion.DatabaseQueryFilter, properties notion.DatabaseProperties) error {
+	if len(filter.And) > 0 || len(filter.Or) > 0 {
+		for _, operand := range append(filter.And, filter.Or...) {
+			if err := validateFilter(operand, properties); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	conditions := filterConditions(filter.DatabaseQueryPropertyFilter)
+
+	if filter.Timestamp != "" {
+		if !isTimestamp(string(filter.Timestamp)) {
+			return fmt.Errorf("unknown timestamp %s. use created_time or last_edited_time", filter.Timestamp)
+		}
+		if len(conditions) != 1 || conditions[0] != string(filter.Timestamp) {
+			return fmt.Errorf("the %s timestamp filter needs a %s condition", filter.Timestamp, filter.Timestamp)
+		}
+		return nil
+	}
+
+	property, ok := properties[filter.Property]
+	if !ok {
+		return unknownProperty(filter.Property, properties)
+	}
+
+	if len(conditions) != 1 || conditions[0] != string(property.Type) {
+		return fmt.Errorf("%s is a %s property, it needs a %s condition", filter.Property, property.Type, property.Type)
+	}
+
+	return nil
+}
+
+// filterConditions returns the types of the conditions set in a filter.
+func filterConditions(filter notion.DatabaseQueryPropertyFilter) []string {
+	conditions := []string{}
+
+	for condition, set := range map[string]bool{
+		string(notion.DBPropTypeTitle):          filter.Title != nil,
+		string(notion.DBPropTypeRichText):       filter.RichText != nil,
+		string(notion.DBPropTypeURL):            filter.URL != nil,
+		string(notion.DBPropTypeEmail):          filter.Email != nil,
+		string(notion.DBPropTypePhoneNumber):    filter.PhoneNumber != nil,
+		string(notion.DBPropTypeDate):           filter.Date != nil,
+		string(notion.DBPropTypeCreatedTime):    filter.CreatedTime != nil,
+		string(notion.DBPropTypeLastEditedTime): filter.LastEditedTime != nil,
+		string(notion.DBPropTypeNumber):         filter.Number != nil,
+		string(notion.DBPropTypeCheckbox):       filter.Checkbox != nil,
+		string(notion.DBPropTypeSelect):         filter.Select != nil,
+		string(notion.DBPropTypeMultiSelect):    filter.MultiSelect != nil,
+		string(notion.DBPropTypeStatus):         filter.Status != nil,
+		string(notion.DBPropTypePeople):         filter.People != nil,
+		string(notion.DBPropTypeFiles):          filter.Files != nil,
+		string(notion.DBPropTypeRelation):       filter.Relation != nil,
+		string(notion.DBPropTypeFormula):        filter.Formula != nil,
+		string(notion.DBPropTypeRollup):         filter.Rollup != nil,
+		string(notion.DBPropTypeCreatedBy):      filter.CreatedBy != nil,
+		string(notion.DBPropTypeLastEditedBy):   filter.LastEditedBy != nil,
+	} {
+		if set {
+			conditions = append(conditions, condition)
+		}
+	}
+
+	return conditions
+}
+
+// propertyName returns the name of a database property, matching it regardless of its case.
+func propertyName(name string, properties notion.DatabaseProperties) (string, bool) {
+	if _, ok := properties[name]; ok {
+		return name, true
+	}
+
+	for propertyName := range properties {
+		if strings.EqualFold(propertyName, name) {
+			return propertyName, true
+		}
+	}
+
+	return "", false
+}
+
+func isTimestamp(name string) bool {
+	name = strings.ToLower(name)
+	return name == notion.TimestampCreatedTime || name == notion.TimestampLastEditedTime
+}
+
+func unknownProperty(name string, properties notion.DatabaseProperties) error {
+	names := make([]string, 0, len(properties))
+	for propertyName := range properties {
+		names = append(names, propertyName)
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("unknown property %s. available properties: %s", name, strings.Join(names, ", "))
+}
+
+// filter builds the Notion filter of an expression, using the type of the properties in the database.
+func (e *filterExpression) filter(properties notion.DatabaseProperties) (notion.DatabaseQueryFilter, error) {
+	if e.operator != "" {
+		operands := []notion.DatabaseQueryFilter{}
+		for _, operand := range e.operands {
+			filter, err := operand.filter(properties)
+			if err != nil {
+				return notion.DatabaseQueryFilter{}, err
+			}
+			operands = append(operands, filter)
+		}
+
+		if e.operator == "and" {
+			return notion.DatabaseQueryFilter{And: operands}, nil
+		}
+		return notion.DatabaseQueryFilter{Or: operands}, nil
+	}
+
+	name, ok := propertyName(e.property, properties)
+	if !ok {
+		if !isTimestamp(e.property) {
+			return notion.DatabaseQueryFilter{}, unknownProperty(e.property, properties)
+		}
+
+		timestamp := strings.ToLower(e.property)
+		date, err := e.dateFilter()
+		if err != nil {
+			return notion.DatabaseQueryFilter{}, err
+		}
+
+		filter := notion.DatabaseQueryFilter{Timestamp: notion.Timestamp(timestamp)}
+		if timestamp == notion.TimestampCreatedTime {
+			filter.CreatedTime = date
+		} else {
+			filter.LastEditedTime = date
+		}
+		return filter, nil
+	}
+
+	filter := notion.DatabaseQueryFilter{Property: name}
+	var err error
+
+	switch property := properties[name]; property.Type {
+	case notion.DBPropTypeTitle:
+		filter.Title, err = e.textFilter()
+	case notion.DBPropTypeRichText:
+		filter.RichText, err = e.textFilter()
+	case notion.DBPropTypeURL:
+		filter.URL, err = e.textFilter()
+	case notion.DBPropTypeEmail:
+		filter.Email, err = e.textFilter()
+	case notion.DBPropTypePhoneNumber:
+		filter.PhoneNumber, err = e.textFilter()
+	case notion.DBPropTypeNumber:
+		filter.Number, err = e.numberFilter()
+	case notion.DBPropTypeCheckbox:
+		filter.Checkbox, err = e.checkboxFilter()
+	case notion.DBPropTypeSelect:
+		filter.Select, err = e.selectFilter()
+	case notion.DBPropTypeStatus:
+		var selectFilter *notion.SelectDatabaseQueryFilter
+		selectFilter, err = e.selectFilter()
+		if selectFilter != nil {
+			filter.Status = (*notion.StatusDatabaseQueryFilter)(selectFilter)
+		}
+	case notion.DBPropTypeMultiSelect:
+		var listFilter *notion.PeopleDatabaseQueryFilter
+		listFilter, err = e.listFilter()
+		if listFilter != nil {
+			filter.MultiSelect = (*notion.MultiSelectDatabaseQueryFilter)(listFilter)
+		}
+	case notion.DBPropTypePeople:
+		filter.People, err = e.listFilter()
+	case notion.DBPropTypeCreatedBy:
+		filter.CreatedBy, err = e.listFilter()
+	case notion.DBPropTypeLastEditedBy:
+		filter.LastEditedBy, err = e.listFilter()
+	case notion.DBPropTypeRelation:
+		var listFilter *notion.PeopleDatabaseQueryFilter
+		listFilter, err = e.listFilter()
+		if listFilter != nil {
+			filter.Relation = (*notion.RelationDatabaseQueryFilter)(listFilter)
+		}
+	case notion.DBPropTypeFiles:
+		switch e.comparison {
+		case comparisonEmpty:
+			filter.Files = &notion.FilesDatabaseQueryFilter{IsEmpty: true}
+		case comparisonNotEmpty:
+			filter.Files = &notion.FilesDatabaseQueryFilter{IsNotEmpty: true}
+		default:
+			err = e.unsupported("is empty, is not empty")
+		}
+	case notion.DBPropTypeDate:
+		filter.Date, err = e.dateFilter()
+	case notion.DBPropTypeCreatedTime:
+		filter.CreatedTime, err = e.dateFilter()
+	case notion.DBPropTypeLastEditedTime:
+		filter.LastEditedTime, err = e.dateFilter()
+	default:
+		err = fmt.Errorf("%s properties can not be filtered with an expression, use a Notion filter JSON", property.Type)
+	}
+
+	if err != nil {
+		return notion.DatabaseQueryFilter{}, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return filter, nil
+}
+
+func (e *filterExpression) unsupported(comparisons string) error {
+	return fmt.Errorf("unsupported comparison %s. use %s", e.comparison, comparisons)
+}
+
+func (e *filterExpression) textFilter() (*notion.TextPropertyFilter, error) {
+	switch e.comparison {
+	case comparisonEquals:
+		return &notion.TextPropertyFilter{Equals: e.value}, nil
+	case comparisonNotEquals:
+		return &notion.TextPropertyFilter{DoesNotEqual: e.value}, nil
+	case comparisonContains:
+		return &notion.TextPropertyFilter{Contains: e.value}, nil
+	case comparisonNotContains:
+		return &notion.TextPropertyFilter{DoesNotContain: e.value}, nil
+	case comparisonStartsWith:
+		return &notion.TextPropertyFilter{StartsWith: e.value}, nil
+	case comparisonEndsWith:
+		return &notion.TextPropertyFilter{EndsWith: e.value}, nil
+	case comparisonEmpty:
+		return &notion.TextPropertyFilter{IsEmpty: true}, nil
+	case comparisonNotEmpty:
+		return &notion.TextPropertyFilter{IsNotEmpty: true}, nil
+	default:
+		return nil, e.unsupported("=, !=, contains, !contains, starts_with, ends_with, is empty, is not empty")
+	}
+}
+
+func (e *filterExpression) numberFilter() (*notion.NumberDatabaseQueryFilter, error) {
+	switch e.comparison {
+	case comparisonEmpty:
+		return &notion.NumberDatabaseQueryFilter{IsEmpty: true}, nil
+	case comparisonNotEmpty:
+		return &notion.NumberDatabaseQueryFilter{IsNotEmpty: true}, nil
+	}
+
+	number, err := strconv.Atoi(e.value)
+	if err != nil {
+		if _, floatErr := strconv.ParseFloat(e.value, 64); floatErr == nil {
+			return nil, fmt.Errorf("number filters only support whole numbers, got %s", e.value)
+		}
+		return nil, fmt.Errorf("invalid number %s", e.value)
+	}
+
+	switch e.comparison {
+	case comparisonEquals:
+		return &notion.NumberDatabaseQueryFilter{Equals: &number}, nil
+	case comparisonNotEquals:
+		return &notion.NumberDatabaseQueryFilter{DoesNotEqual: &number}, nil
+	case comparisonGreater:
+		return &notion.NumberDatabaseQueryFilter{GreaterThan: &number}, nil
+	case comparisonGreaterOrEqual:
+		return &notion.NumberDatabaseQueryFilter{GreaterThanOrEqualTo: &number}, nil
+	case comparisonLess:
+		return &notion.NumberDatabaseQueryFilter{LessThan: &number}, nil
+	case comparisonLessOrEqual:
+		return &notion.NumberDatabaseQueryFilter{LessThanOrEqualTo: &number}, nil
+	default:
+		return nil, e.unsupported("=, !=, >, >=, <, <=, is empty, is not empty")
+	}
+}
+
+func (e *filterExpression) checkboxFilter() (*notion.CheckboxDatabaseQueryFilter, error) {
+	checked, err := strconv.ParseBool(e.value)
+	if err != nil && e.value != "" {
+		return nil, fmt.Errorf("invalid checkbox value %s. use true or false", e.value)
+	}
+
+	switch e.comparison {
+	case comparisonEquals:
+		return &notion.CheckboxDatabaseQueryFilter{Equals: &checked}, nil
+	case comparisonNotEquals:
+		return &notion.CheckboxDatabaseQueryFilter{DoesNotEqual: &checked}, nil
+	default:
+		return nil, e.unsupported("=, !=")
+	}
+}
+
+func (e *filterExpression) selectFilter() (*notion.SelectDatabaseQueryFilter, error) {
+	switch e.comparison {
+	case comparisonEquals:
+		return &notion.SelectDatabaseQueryFilter{Equals: e.value}, nil
+	case comparisonNotEquals:
+		return &notion.SelectDatabaseQueryFilter{DoesNotEqual: e.value}, nil
+	case comparisonEmpty:
+		return &notion.SelectDatabaseQueryFilter{IsEmpty: true}, nil
+	case comparisonNotEmpty:
+		return &notion.SelectDatabaseQueryFilter{IsNotEmpty: true}, nil
+	default:
+		return nil, e.unsupported("=, !=, is empty, is not empty")
+	}
+}
+
+// listFilter builds the filter of the properties holding a list: multi-select, people and relations.
+func (e *filterExpression) listFilter() (*notion.PeopleDatabaseQueryFilter, error) {
+	switch e.comparison {
+	case comparisonContains:
+		return &notion.PeopleDatabaseQueryFilter{Contains: e.value}, nil
+	case comparisonNotContains:
+		return &notion.PeopleDatabaseQueryFilter{DoesNotContain: e.value}, nil
+	case comparisonEmpty:
+		return &notion.PeopleDatabaseQueryFilter{IsEmpty: true}, nil
+	case comparisonNotEmpty:
+		return &notion.PeopleDatabaseQueryFilter{IsNotEmpty: true}, nil
+	default:
+		return nil, e.unsupported("contains, !contains, is empty, is not empty")
+	}
+}
+
+func (e *filterExpression) dateFilter() (*notion.DatePropertyFilter, error) {
+	switch e.comparison {
+	case comparisonEmpty:
+		return &notion.DatePropertyFilter{IsEmpty: true}, nil
+	case comparisonNotEmpty:
+		return &notion.DatePropertyFilter{IsNotEmpty: true}, nil
+	}
+
+	date, err := time.Parse("2006-01-02", e.value)
+	if err != nil {
+		date, err = time.Parse(time.RFC3339, e.value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %s. use 2006-01-02 or 2006-01-02T15:04:05Z07:00", e.value)
+	}
+
+	switch e.comparison {
+	case comparisonEquals:
+		return &notion.DatePropertyFilter{Equals: &date}, nil
+	case comparisonGreater:
+		return &notion.DatePropertyFilter{After: &date}, nil
+	case comparisonGreaterOrEqual:
+		return &notion.DatePropertyFilter{OnOrAfter: &date}, nil
+	case comparisonLess:
+		return &notion.DatePropertyFilter{Before: &date}, nil
+	case comparisonLessOrEqual:
+		return &notion.DatePropertyFilter{OnOrBefore: &date}, nil
+	default:
+		return nil, e.unsupported("=, >, >=, <, <=, is empty, is not empty")
+	}
+}
+
+// filterToken is a word, a quoted text, a comparison or a parenthesis of a filter expression.
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+// filterParser parses filter expressions. AND binds tighter than OR, parentheses group conditions.
+// Property names and values with spaces are quoted: `"Due date" >= 2024-01-01`.
+type filterParser struct {
+	tokens   []filterToken
+	position int
+}
+
+func (p *filterParser) tokenize(text string) error {
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			p.tokens = append(p.tokens, filterToken{text: string(r)})
+			i++
+		case r == '"':
+			value := []rune{}
+			i++
+			for ; i < len(runes) && runes[i] != '"'; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				value = append(value, runes[i])
+			}
+			if i == len(runes) {
+				return errors.New("missing closing quote")
+			}
+			p.tokens = append(p.tokens, filterToken{text: string(value), quoted: true})
+			i++
+		case r == '!' && i+1 < len(runes) && unicode.IsLetter(runes[i+1]):
+			start := i
+			for i++; i < len(runes) && isWordRune(runes[i]); i++ {
+			}
+			p.tokens = append(p.tokens, filterToken{text: string(runes[start:i])})
+		case strings.ContainsRune("=!<>", r):
+			start := i
+			for ; i < len(runes) && strings.ContainsRune("=!<>", runes[i]); i++ {
+			}
+			p.tokens = append(p.tokens, filterToken{text: string(runes[start:i])})
+		default:
+			start := i
+			for ; i < len(runes) && isWordRune(runes[i]); i++ {
+			}
+			p.tokens = append(p.tokens, filterToken{text: string(runes[start:i])})
+		}
+	}
+
+	return nil
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(`()"=!<>`, r)
+}
+
+func (p *filterParser) parse() (*filterExpression, error) {
+	expression, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if token, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected %s", token.text)
+	}
+
+	return expression, nil
+}
+
+func (p *filterParser) parseOr() (*filterExpression, error) {
+	return p.parseJoined("or", p.parseAnd)
+}
+
+func (p *filterParser) parseAnd() (*filterExpression, error) {
+	return p.parseJoined("and", p.parseOperand)
+}
+
+func (p *filterParser) parseJoined(
+	operator string,
+	operand func() (*filterExpression, error),
+) (*filterExpression, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []*filterExpression{first}
+	for p.keyword(operator) {
+		next, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, next)
+	}
+
+	if len(operands) == 1 {
+		return first, nil
+	}
+
+	return &filterExpression{operator: operator, operands: operands}, nil
+}
+
+func (p *filterParser) parseOperand() (*filterExpression, error) {
+	token, ok := p.next()
+	if !ok {
+		return nil, errors.New("missing condition")
+	}
+
+	if token.text == "(" && !token.quoted {
+		expression, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.next(); !ok || closing.text != ")" || closing.quoted {
+			return nil, errors.New("missing closing parenthesis")
+		}
+		return expression, nil
+	}
+
+	if !token.quoted && (token.text == ")" || !isWordRune([]rune(token.text)[0])) {
+		return nil, fmt.Errorf("expected a property, got %s", token.text)
+	}
+
+	condition := &filterExpression{property: token.text}
+
+	comparison, ok := p.next()
+	if !ok {
+		return nil, fmt.Errorf("missing comparison after %s", token.text)
+	}
+
+	switch text := strings.ToLower(comparison.text); {
+	case comparison.quoted:
+		return nil, fmt.Errorf("expected a comparison after %s, got %q", token.text, comparison.text)
+	case text == "is":
+		condition.comparison = comparisonEmpty
+		if p.keyword("not") {
+			condition.comparison = comparisonNotEmpty
+		}
+		if !p.keyword("empty") {
+			is := strings.TrimSuffix(condition.comparison, " empty")
+			return nil, fmt.Errorf("expected empty after %s %s", token.text, is)
+		}
+		return condition, nil
+	case text == comparisonEquals, text == comparisonNotEquals, text == comparisonGreater,
+		text == comparisonGreaterOrEqual, text == comparisonLess, text == comparisonLessOrEqual,
+		text == comparisonContains, text == comparisonNotContains, text == comparisonStartsWith,
+		text == comparisonEndsWith:
+		condition.comparison = text
+	default:
+		return nil, fmt.Errorf("unknown comparison %s", comparison.text)
+	}
+
+	value, ok := p.next()
+	if !ok || (!value.quoted && (value.text == "(" || value.text == ")")) {
+		return nil, fmt.Errorf("missing value after %s %s", token.text, condition.comparison)
+	}
+	condition.value = value.text
+
+	return condition, nil
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.position >= len(p.tokens) {
+		return filterToken{}, false
+	}
+
+	return p.tokens[p.position], true
+}
+
+func (p *filterParser) next() (filterToken, bool) {
+	token, ok := p.peek()
+	if ok {
+		p.position++
+	}
+
+	return token, ok
+}
+
+// keyword consumes the next token when it is the unquoted keyword, in any case.
+func (p *filterParser) keyword(keyword string) bool {
+	token, ok := p.peek()
+	if !ok || token.quoted || !strings.EqualFold(token.text, keyword) {
+		return false
+	}
+
+	p.position++
+
+	return true
+}
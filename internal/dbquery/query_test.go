@@ -0,0 +1,207 @@
+package dbquery
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var queryProperties = notion.DatabaseProperties{
+	"Name":     {Type: notion.DBPropTypeTitle},
+	"Status":   {Type: notion.DBPropTypeStatus},
+	"Priority": {Type: notion.DBPropTypeSelect},
+	"Due date": {Type: notion.DBPropTypeDate},
+	"Points":   {Type: notion.DBPropTypeNumber},
+	"Done":     {Type: notion.DBPropTypeCheckbox},
+	"Tags":     {Type: notion.DBPropTypeMultiSelect},
+	"Owner":    {Type: notion.DBPropTypePeople},
+	"Files":    {Type: notion.DBPropTypeFiles},
+	"Score":    {Type: notion.DBPropTypeFormula},
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		sorts    string
+		expected string
+	}{
+		{
+			name:     "no filter nor sorts",
+			expected: `null`,
+		},
+		{
+			name:     "single condition",
+			filter:   "Status = Done",
+			expected: `{"filter":{"property":"Status","status":{"equals":"Done"}}}`,
+		},
+		{
+			name:   "AND binds tighter than OR",
+			filter: `Status = Done AND "Due date" >= 2024-01-01 or Priority != Low`,
+			expected: `{"filter":{"or":[
+				{"and":[
+					{"property":"Status","status":{"equals":"Done"}},
+					{"property":"Due date","date":{"on_or_after":"2024-01-01T00:00:00Z"}}
+				]},
+				{"property":"Priority","select":{"does_not_equal":"Low"}}
+			]}}`,
+		},
+		{
+			name:   "parentheses",
+			filter: `status = "In progress" AND (Points > 3 OR Tags contains urgent)`,
+			expected: `{"filter":{"and":[
+				{"property":"Status","status":{"equals":"In progress"}},
+				{"or":[
+					{"property":"Points","number":{"greater_than":3}},
+					{"property":"Tags","multi_select":{"contains":"urgent"}}
+				]}
+			]}}`,
+		},
+		{
+			name:   "text, checkbox, people and files",
+			filter: `Name starts_with "Q&A" AND Done = false AND Owner is not empty AND Files is empty`,
+			expected: `{"filter":{"and":[
+				{"property":"Name","title":{"starts_with":"Q&A"}},
+				{"property":"Done","checkbox":{"equals":false}},
+				{"property":"Owner","people":{"is_not_empty":true}},
+				{"property":"Files","files":{"is_empty":true}}
+			]}}`,
+		},
+		{
+			name:     "timestamps",
+			filter:   "created_time < 2024-06-01T10:00:00Z",
+			expected: `{"filter":{"timestamp":"created_time","created_time":{"before":"2024-06-01T10:00:00Z"}}}`,
+		},
+		{
+			name:     "JSON filter",
+			filter:   `{"property":"Score","formula":{"number":{"greater_than":10}}}`,
+			expected: `{"filter":{"property":"Score","formula":{"number":{"greater_than":10}}}}`,
+		},
+		{
+			name: "JSON filter with dates",
+			filter: `{"or":[
+				{"property":"Due date","date":{"after":"2024-01-01"}},
+				{"property":"Points","number":{"equals":1}}
+			]}`,
+			expected: `{"filter":{"or":[
+				{"property":"Due date","date":{"after":"2024-01-01T00:00:00Z"}},
+				{"property":"Points","number":{"equals":1}}
+			]}}`,
+		},
+		{
+			name:  "sorts",
+			sorts: "due date desc, Name, last_edited_time ascending",
+			expected: `{"sorts":[
+				{"property":"Due date","direction":"descending"},
+				{"property":"Name","direction":"ascending"},
+				{"timestamp":"last_edited_time","direction":"ascending"}
+			]}`,
+		},
+		{
+			name:     "JSON sorts",
+			sorts:    `[{"property":"Points","direction":"descending"}]`,
+			expected: `{"sorts":[{"property":"Points","direction":"descending"}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := ParseFilter(test.filter)
+			require.NoError(t, err)
+			sorts, err := ParseSorts(test.sorts)
+			require.NoError(t, err)
+
+			query, err := Build(filter, sorts, queryProperties)
+			require.NoError(t, err)
+
+			actual, err := json.Marshal(query)
+			require.NoError(t, err)
+			assert.JSONEq(t, test.expected, string(actual))
+		})
+	}
+}
+
+func TestBuild_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		sorts    string
+		expected string
+	}{
+		{
+			name:     "unknown property",
+			filter:   "State = Done",
+			expected: "unknown property State. available properties: Done, Due date, Files, Name, Owner, Points",
+		},
+		{
+			name:     "comparison not supported by the type",
+			filter:   "Tags = urgent",
+			expected: "Tags: unsupported comparison =. use contains, !contains, is empty, is not empty",
+		},
+		{name: "decimal numbers", filter: "Points > 2.5", expected: "number filters only support whole numbers"},
+		{name: "invalid dates", filter: `"Due date" > tomorrow`, expected: "invalid date tomorrow"},
+		{name: "invalid checkbox", filter: "Done = maybe", expected: "invalid checkbox value maybe"},
+		{
+			name:     "formula in an expression",
+			filter:   "Score > 1",
+			expected: "formula properties can not be filtered with an expression",
+		},
+		{
+			name:     "JSON filter with an unknown property",
+			filter:   `{"property":"State","status":{"equals":"Done"}}`,
+			expected: "unknown property State",
+		},
+		{
+			name:     "JSON filter with a condition for another type",
+			filter:   `{"and":[{"property":"Status","select":{"equals":"Done"}}]}`,
+			expected: "Status is a status property, it needs a status condition",
+		},
+		{name: "sort by an unknown property", sorts: "Deadline desc", expected: "unknown property Deadline"},
+		{
+			name:     "JSON sorts with an invalid direction",
+			sorts:    `[{"property":"Name","direction":"up"}]`,
+			expected: `invalid sort direction "up"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := ParseFilter(test.filter)
+			require.NoError(t, err)
+			sorts, err := ParseSorts(test.sorts)
+			require.NoError(t, err)
+
+			_, err = Build(filter, sorts, queryProperties)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	tests := []struct {
+		filter   string
+		expected string
+	}{
+		{filter: "Status", expected: "missing comparison after Status"},
+		{filter: "Status ~ Done", expected: "unknown comparison ~"},
+		{filter: "Status =", expected: "missing value after Status ="},
+		{filter: "Status = Done AND", expected: "missing condition"},
+		{filter: "(Status = Done", expected: "missing closing parenthesis"},
+		{filter: "Status = Done)", expected: "unexpected )"},
+		{filter: `Name = "Done`, expected: "missing closing quote"},
+		{filter: "Tags is full", expected: "expected empty after Tags is"},
+		{filter: `{"property":"Status","status":{"equal":"Done"}}`, expected: "failed to parse the filter JSON"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			_, err := ParseFilter(test.filter)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.expected)
+		})
+	}
+}
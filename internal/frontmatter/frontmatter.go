@@ -0,0 +1,143 @@
+package frontmatter
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DateFormat and DateTimeFormat are the formats Obsidian recognises as date and date & time properties.
+	DateFormat     = "2006-01-02"
+	DateTimeFormat = time.RFC3339
+)
+
+// Date is a day without time, written as a date property.
+// A time.Time is written as a date & time property.
+type Date time.Time
+
+// Frontmatter is the YAML block of properties at the top of an Obsidian note.
+// Properties are written in the order they are set.
+type Frontmatter struct {
+	keys   []string
+	values map[string]*yaml.Node
+}
+
+func New() *Frontmatter {
+	return &Frontmatter{values: map[string]*yaml.Node{}}
+}
+
+// Set adds or replaces a property. Supported values are nil, strings, booleans, integers, floats,
+// time.Time, Date, and slices of them, written as lists.
+func (f *Frontmatter) Set(key string, value any) error {
+	node, err := encode(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode property %s. error: %w", key, err)
+	}
+
+	if _, ok := f.values[key]; !ok {
+		f.keys = append(f.keys, key)
+	}
+	f.values[key] = node
+
+	return nil
+}
+
+func (f *Frontmatter) Len() int {
+	return len(f.keys)
+}
+
+// String returns the frontmatter between `---` lines, or an empty string when there are no properties.
+func (f *Frontmatter) String() string {
+	if len(f.keys) == 0 {
+		return ""
+	}
+
+	document := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range f.keys {
+		document.Content = append(document.Content, scalar("!!str", key), f.values[key])
+	}
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString("---\n")
+
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	// The nodes are built from supported values only, encoding them can not fail.
+	_ = encoder.Encode(document)
+	_ = encoder.Close()
+
+	buffer.WriteString("---\n")
+
+	return buffer.String()
+}
+
+func encode(value any) (*yaml.Node, error) {
+	switch v := value.(type) {
+	case nil:
+		return scalar("!!null", ""), nil
+	case string:
+		return scalar("!!str", v), nil
+	case bool:
+		return scalar("!!bool", strconv.FormatBool(v)), nil
+	case int:
+		return scalar("!!int", strconv.Itoa(v)), nil
+	case int64:
+		return scalar("!!int", strconv.FormatInt(v, 10)), nil
+	case float64:
+		return encodeFloat(v), nil
+	case time.Time:
+		// The UTC offset keeps the time zone of the date.
+		return scalar("!!timestamp", v.Format(DateTimeFormat)), nil
+	case Date:
+		return scalar("!!timestamp", time.Time(v).Format(DateFormat)), nil
+	case []string:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = item
+		}
+		return encodeList(items)
+	case []any:
+		return encodeList(v)
+	default:
+		return nil, fmt.Errorf("unsupported value %T", value)
+	}
+}
+
+// encodeFloat writes whole numbers as integers, Notion returns every number as a float.
+func encodeFloat(v float64) *yaml.Node {
+	switch {
+	case math.IsNaN(v):
+		return scalar("!!float", ".nan")
+	case math.IsInf(v, 1):
+		return scalar("!!float", ".inf")
+	case math.IsInf(v, -1):
+		return scalar("!!float", "-.inf")
+	case v == math.Trunc(v) && math.Abs(v) < 1e15:
+		return scalar("!!int", strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return scalar("!!float", strconv.FormatFloat(v, 'g', -1, 64))
+	}
+}
+
+func encodeList(items []any) (*yaml.Node, error) {
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+
+	for _, item := range items {
+		node, err := encode(item)
+		if err != nil {
+			return nil, err
+		}
+		list.Content = append(list.Content, node)
+	}
+
+	return list, nil
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
@@ -0,0 +1,113 @@
+package frontmatter
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// parse reads the properties back from the frontmatter, like Obsidian does.
+func parse(t *testing.T, content string) map[string]any {
+	t.Helper()
+
+	require.True(t, strings.HasPrefix(content, "---\n"), content)
+	require.True(t, strings.HasSuffix(content, "---\n"), content)
+
+	properties := map[string]any{}
+	require.NoError(t, yaml.Unmarshal([]byte(strings.TrimPrefix(content, "---\n")), &properties))
+
+	return properties
+}
+
+func TestFrontmatter_RoundTrip(t *testing.T) {
+	datetime := time.Date(2024, 10, 10, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    any
+		expected any
+	}{
+		{name: "plain string", value: "Hello", expected: "Hello"},
+		{name: "string with colon", value: "Meeting: planning", expected: "Meeting: planning"},
+		{name: "string with quotes", value: `She said "hi" and it's fine`, expected: `She said "hi" and it's fine`},
+		{name: "string with comment", value: "Issue #42", expected: "Issue #42"},
+		{name: "string with hash prefix", value: "#tag", expected: "#tag"},
+		{name: "string with leading dash", value: "- not a list", expected: "- not a list"},
+		{name: "string with newlines", value: "first line\nsecond line", expected: "first line\nsecond line"},
+		{name: "string looking like a number", value: "42", expected: "42"},
+		{name: "string looking like a boolean", value: "true", expected: "true"},
+		{name: "string looking like null", value: "null", expected: "null"},
+		{name: "string looking like a date", value: "2024-10-10", expected: "2024-10-10"},
+		{name: "wikilink", value: "[[Projects/Roadmap.md]]", expected: "[[Projects/Roadmap.md]]"},
+		{name: "empty string", value: "", expected: ""},
+		{name: "integer", value: 3, expected: 3},
+		{name: "whole float", value: 3.0, expected: 3},
+		{name: "negative whole float", value: -12.0, expected: -12},
+		{name: "float", value: 3.25, expected: 3.25},
+		{name: "infinity", value: math.Inf(1), expected: math.Inf(1)},
+		{name: "boolean", value: true, expected: true},
+		{name: "null", value: nil, expected: nil},
+		{name: "date", value: Date(datetime), expected: time.Date(2024, 10, 10, 0, 0, 0, 0, time.UTC)},
+		{name: "datetime", value: datetime, expected: datetime},
+		{
+			name:     "datetime with offset",
+			value:    datetime.In(time.FixedZone("", 2*60*60)),
+			expected: datetime.In(time.FixedZone("", 2*60*60)),
+		},
+		{
+			name:     "list of strings",
+			value:    []string{"a, b", "[[Note]]", "#tag"},
+			expected: []any{"a, b", "[[Note]]", "#tag"},
+		},
+		{name: "list of numbers", value: []any{42.0, 1.5}, expected: []any{42, 1.5}},
+		{name: "empty list", value: []string{}, expected: []any{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			properties := New()
+			require.NoError(t, properties.Set("Property", test.value))
+
+			assert.Equal(t, map[string]any{"Property": test.expected}, parse(t, properties.String()))
+		})
+	}
+}
+
+func TestFrontmatter_String(t *testing.T) {
+	properties := New()
+	require.NoError(t, properties.Set("Title", "Weekly: sync"))
+	require.NoError(t, properties.Set("Count", 2.0))
+	require.NoError(t, properties.Set("Tags", []string{"work", "notes"}))
+	require.NoError(t, properties.Set("Due", Date(time.Date(2024, 10, 10, 0, 0, 0, 0, time.UTC))))
+	require.NoError(t, properties.Set("Empty", nil))
+	require.NoError(t, properties.Set("Count", 3.0))
+	require.NoError(t, properties.Set("Key: with colon", "value"))
+
+	assert.Equal(t, 6, properties.Len())
+	assert.Equal(t, `---
+Title: 'Weekly: sync'
+Count: 3
+Tags:
+  - work
+  - notes
+Due: 2024-10-10
+Empty:
+'Key: with colon': value
+---
+`, properties.String())
+}
+
+func TestFrontmatter_Empty(t *testing.T) {
+	assert.Empty(t, New().String())
+}
+
+func TestFrontmatter_Unsupported(t *testing.T) {
+	err := New().Set("Property", struct{}{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to encode property Property")
+}
@@ -0,0 +1,187 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// NotionBaseURL is the Notion API URL used by the Notion client.
+	NotionBaseURL    = "https://api.notion.com/v1"
+	DefaultUserAgent = "n2o"
+	DefaultTimeout   = time.Minute
+)
+
+type Options struct {
+	// Proxy is the URL of the HTTP proxy. When empty, the proxy is read from the
+	// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxy string
+	// CABundle is a PEM file with the certificates trusted on top of the system ones.
+	CABundle string
+	// Timeout limits every request, including reading the response body. Zero means no timeout.
+	Timeout time.Duration
+	// UserAgent replaces the User-Agent header of the requests when not empty.
+	UserAgent string
+}
+
+// NewTransport returns an http.RoundTripper sending the requests through the proxy,
+// trusting the CA bundle and applying the timeout and User-Agent of the options.
+func NewTransport(options Options) (http.RoundTripper, error) {
+	base, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected default transport %T", http.DefaultTransport)
+	}
+	transport := base.Clone()
+
+	if options.Proxy != "" {
+		proxy, err := parseURL(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy %s. error: %w", options.Proxy, err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if options.CABundle != "" {
+		pool, err := loadCABundle(options.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		transport.TLSClientConfig.RootCAs = pool
+	}
+
+	var next http.RoundTripper = transport
+
+	if options.Timeout > 0 {
+		next = &timeoutTransport{next: next, timeout: options.Timeout}
+	}
+
+	if options.UserAgent != "" {
+		next = &userAgentTransport{next: next, userAgent: options.UserAgent}
+	}
+
+	return next, nil
+}
+
+// NewBaseURLTransport returns an http.RoundTripper sending the requests for the Notion API to baseURL,
+// for example a local stand-in of the Notion API or a gateway.
+func NewBaseURLTransport(next http.RoundTripper, baseURL string) (http.RoundTripper, error) {
+	target, err := parseURL(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Notion API base URL %s. error: %w", baseURL, err)
+	}
+
+	notion, _ := url.Parse(NotionBaseURL)
+
+	return &baseURLTransport{next: next, from: notion, to: target}, nil
+}
+
+// parseURL parses an absolute http or https URL.
+func parseURL(value string) (*url.URL, error) {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme %q. use http or https", parsed.Scheme)
+	}
+
+	if parsed.Host == "" {
+		return nil, errors.New("missing host")
+	}
+
+	return parsed, nil
+}
+
+func loadCABundle(path string) (*x509.CertPool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle %s. error: %w", path, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", path)
+	}
+
+	return pool, nil
+}
+
+type timeoutTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *timeoutTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The timeout keeps running while the body is read, it is released when the body is closed.
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+type userAgentTransport struct {
+	next      http.RoundTripper
+	userAgent string
+}
+
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	clone.Header.Set("User-Agent", t.userAgent)
+
+	return t.next.RoundTrip(clone)
+}
+
+type baseURLTransport struct {
+	next http.RoundTripper
+	from *url.URL
+	to   *url.URL
+}
+
+func (t *baseURLTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host != t.from.Host || !strings.HasPrefix(req.URL.Path, t.from.Path) {
+		return t.next.RoundTrip(req)
+	}
+
+	clone := req.Clone(req.Context())
+	clone.URL.Scheme = t.to.Scheme
+	clone.URL.Host = t.to.Host
+	clone.URL.Path = strings.TrimSuffix(t.to.Path, "/") + strings.TrimPrefix(req.URL.Path, t.from.Path)
+	clone.URL.RawPath = ""
+	clone.Host = t.to.Host
+
+	return t.next.RoundTrip(clone)
+}
@@ -0,0 +1,170 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRoundtripper struct {
+	fn func(*http.Request) (*http.Response, error)
+}
+
+func (m *mockRoundtripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return m.fn(r)
+}
+
+func get(t *testing.T, transport http.RoundTripper, url string) (*http.Response, error) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	if err == nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+
+	return resp, err
+}
+
+func TestBaseURLTransport(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		url      string
+		expected string
+	}{
+		{
+			name:     "sends the Notion API requests to the base URL",
+			baseURL:  "http://localhost:8080/v1",
+			url:      "https://api.notion.com/v1/pages/000000?page_size=10",
+			expected: "http://localhost:8080/v1/pages/000000?page_size=10",
+		},
+		{
+			name:     "keeps the path of the base URL",
+			baseURL:  "https://gateway.example.com/notion/",
+			url:      "https://api.notion.com/v1/search",
+			expected: "https://gateway.example.com/notion/search",
+		},
+		{
+			name:     "does not change other requests",
+			baseURL:  "http://localhost:8080/v1",
+			url:      "https://images.example.com/v1/cover.png",
+			expected: "https://images.example.com/v1/cover.png",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sent string
+			transport, err := NewBaseURLTransport(&mockRoundtripper{fn: func(r *http.Request) (*http.Response, error) {
+				sent = r.URL.String()
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+			}}, test.baseURL)
+			require.NoError(t, err)
+
+			_, err = get(t, transport, test.url)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, sent)
+		})
+	}
+
+	t.Run("fails for invalid base URLs", func(t *testing.T) {
+		_, err := NewBaseURLTransport(http.DefaultTransport, "localhost:8080")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid Notion API base URL")
+	})
+}
+
+func TestNewTransport(t *testing.T) {
+	t.Run("sets the User-Agent", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.WriteString(w, r.UserAgent())
+		}))
+		defer srv.Close()
+
+		transport, err := NewTransport(Options{UserAgent: "n2o-test"})
+		require.NoError(t, err)
+
+		resp, err := get(t, transport, srv.URL)
+		require.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "n2o-test", string(body))
+	})
+
+	t.Run("times out slow requests", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}))
+		defer srv.Close()
+
+		transport, err := NewTransport(Options{Timeout: 10 * time.Millisecond})
+		require.NoError(t, err)
+
+		_, err = get(t, transport, srv.URL)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "context deadline exceeded")
+	})
+
+	t.Run("sends the requests through the proxy", func(t *testing.T) {
+		var proxied string
+		proxy := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			proxied = r.URL.String()
+		}))
+		defer proxy.Close()
+
+		transport, err := NewTransport(Options{Proxy: proxy.URL})
+		require.NoError(t, err)
+
+		_, err = get(t, transport, "http://api.example.com/v1/pages")
+		require.NoError(t, err)
+		assert.Equal(t, "http://api.example.com/v1/pages", proxied)
+	})
+
+	t.Run("trusts the CA bundle", func(t *testing.T) {
+		srv := httptest.NewTLSServer(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+		defer srv.Close()
+
+		bundle := filepath.Join(t.TempDir(), "ca.pem")
+		certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+		require.NoError(t, os.WriteFile(bundle, certificate, 0600))
+
+		transport, err := NewTransport(Options{})
+		require.NoError(t, err)
+		_, err = get(t, transport, srv.URL)
+		require.Error(t, err)
+
+		transport, err = NewTransport(Options{CABundle: bundle})
+		require.NoError(t, err)
+		resp, err := get(t, transport, srv.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("fails for invalid settings", func(t *testing.T) {
+		_, err := NewTransport(Options{Proxy: "ftp://proxy.example.com"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid proxy")
+
+		bundle := filepath.Join(t.TempDir(), "ca.pem")
+		require.NoError(t, os.WriteFile(bundle, []byte("not a certificate"), 0600))
+
+		_, err = NewTransport(Options{CABundle: bundle})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no PEM certificates found")
+	})
+}
@@ -1,13 +1,16 @@
 package migrator
 
 import (
+	"strings"
 	"sync"
 )
 
 type Cache struct {
 	storage map[string]*Page
 	working map[string]bool
-	mu      sync.RWMutex
+	// paths holds the page ID of every note path, lowercased, to give different pages different notes.
+	paths map[string]string
+	mu    sync.RWMutex
 }
 
 func (c *Cache) Get(value string) (*Page, bool) {
@@ -36,11 +39,34 @@ func (c *Cache) IsWorking(key string) bool {
 	return c.working[key]
 }
 
+// Claim reserves a note path for a page and returns it. When another page has the path,
+// the alternatives are tried in order until one is free. Claiming the same path twice for a page returns the same path.
+func (c *Cache) Claim(pageID, pagePath string, alternative func(attempt int) string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	candidate := pagePath
+	for attempt := 1; ; attempt++ {
+		owner, ok := c.paths[strings.ToLower(candidate)]
+		if !ok || owner == pageID {
+			c.paths[strings.ToLower(candidate)] = pageID
+			return candidate
+		}
+
+		next := alternative(attempt)
+		if next == candidate {
+			return candidate
+		}
+		candidate = next
+	}
+}
+
 func NewCache() *Cache {
 	storage := map[string]*Page{}
 	working := map[string]bool{}
 	return &Cache{
 		storage: storage,
 		working: working,
+		paths:   map[string]string{},
 	}
 }
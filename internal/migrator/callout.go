@@ -0,0 +1,83 @@
+package migrator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/dstotijn/go-notion"
+)
+
+// emojiVariationSelector makes a character display as an emoji, Notion returns some emoji with and without it.
+const emojiVariationSelector = "\ufe0f"
+
+// writeCallout writes a Notion callout as an Obsidian callout, https://help.obsidian.md/Editing+and+formatting/Callouts.
+// The text of the callout is its body and the children are written inside it.
+func (m *migrator) writeCallout(ctx context.Context, parentPage *Page, block *notion.CalloutBlock) error {
+	buffer := parentPage.buffer
+
+	calloutType, title := m.calloutType(block)
+
+	buffer.WriteString("> [!" + calloutType + "]")
+	if title != "" {
+		buffer.WriteString(" " + title)
+	}
+	buffer.WriteString("\n")
+
+	body, err := m.capture(parentPage, func() error {
+		return m.writeRichText(ctx, parentPage, block.RichText)
+	})
+	if err != nil {
+		return err
+	}
+	if body != "" {
+		buffer.WriteString(prefixLines(body+"\n", quotePrefix))
+	}
+
+	if err = m.writeChrildren(ctx, parentPage, block, quotePrefix); err != nil {
+		return err
+	}
+
+	// The empty line ends the callout, otherwise the next callout or paragraph would be part of it.
+	parentPage.buffer.WriteString("\n")
+
+	return nil
+}
+
+// calloutType returns the Obsidian callout type of a callout, from its icon emoji or its color.
+// An emoji without a callout type is kept as the title of the callout. File and external icons are ignored.
+func (m *migrator) calloutType(block *notion.CalloutBlock) (string, string) {
+	var emoji string
+	if block.Icon != nil && block.Icon.Type == notion.IconTypeEmoji && block.Icon.Emoji != nil {
+		emoji = *block.Icon.Emoji
+	}
+
+	if emoji != "" {
+		if calloutType, ok := m.lookupCalloutType(strings.TrimSuffix(emoji, emojiVariationSelector)); ok {
+			return calloutType, ""
+		}
+	}
+
+	color := strings.TrimSuffix(string(block.Color), "_background")
+	if calloutType, ok := m.lookupCalloutType(color); ok {
+		return calloutType, emoji
+	}
+
+	return config.DefaultCalloutType, emoji
+}
+
+// lookupCalloutType finds the callout type of an emoji or a color, the configured types override the default ones.
+// Emoji are matched with and without the variation selector, and colors with and without `_background`.
+func (m *migrator) lookupCalloutType(key string) (string, bool) {
+	keys := []string{key, key + emojiVariationSelector, key + "_background"}
+
+	for _, types := range []map[string]string{m.config.CalloutTypes, config.DefaultCalloutTypes} {
+		for _, key := range keys {
+			if calloutType, ok := types[key]; ok {
+				return calloutType, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,169 @@
+package migrator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/GustavoCaso/n2o/internal/frontmatter"
+	"github.com/dstotijn/go-notion"
+	"github.com/itchyny/timefmt-go"
+)
+
+// localTime returns the time of a Notion date in the configured time zone.
+// Dates without time are days, they are not converted.
+func (m *migrator) localTime(date notion.DateTime) time.Time {
+	if !date.HasTime() || m.config.TimeZone == nil {
+		return date.Time
+	}
+
+	return date.Time.In(m.config.TimeZone)
+}
+
+// inTimeZone returns a Notion date in the configured time zone or, without one, in the time zone
+// Notion returns with the date. Dates without time are days, they are not converted.
+func (m *migrator) inTimeZone(date notion.Date) notion.Date {
+	location := m.config.TimeZone
+	if location == nil && date.TimeZone != nil {
+		zone, err := time.LoadLocation(*date.TimeZone)
+		if err != nil {
+			m.debugLog(fmt.Sprintf("unknown time zone %s, the date is kept as returned by Notion\n", *date.TimeZone))
+			return date
+		}
+		location = zone
+	}
+
+	if location == nil {
+		return date
+	}
+
+	convert := func(dateTime notion.DateTime) notion.DateTime {
+		if !dateTime.HasTime() {
+			return dateTime
+		}
+		return notion.NewDateTime(dateTime.Time.In(location), true)
+	}
+
+	converted := date
+	converted.Start = convert(date.Start)
+	if date.End != nil {
+		end := convert(*date.End)
+		converted.End = &end
+	}
+
+	return converted
+}
+
+// dateRange returns the value of a Notion date, date ranges are written as an ISO 8601 interval: `start/end`.
+func (m *migrator) dateRange(date notion.Date) any {
+	date = m.inTimeZone(date)
+	if date.End == nil {
+		return m.dateProperty(date.Start)
+	}
+
+	return fmt.Sprintf("%s/%s", m.formatDate(date.Start), m.formatDate(*date.End))
+}
+
+// addDate adds a date property to the frontmatter. With DateRangeSplit, the start and end of the date
+// are written as `<key>_start` and `<key>_end`, the end only when the date is a range.
+func (m *migrator) addDate(properties *frontmatterProperties, name string, date notion.Date) {
+	if m.config.DateRange != config.DateRangeSplit {
+		properties.add(name, m.dateRange(date))
+		return
+	}
+
+	date = m.inTimeZone(date)
+
+	var end any
+	if date.End != nil {
+		end = m.dateProperty(*date.End)
+	}
+
+	properties.addRange(name, m.dateProperty(date.Start), end)
+}
+
+// propertyDate returns the date of a date property, of a formula or rollup with a date result,
+// or of the creation and last edition times of the page.
+func propertyDate(value notion.DatabasePageProperty) (notion.Date, bool) {
+	switch value.Type {
+	case notion.DBPropTypeDate:
+		if value.Date != nil {
+			return *value.Date, true
+		}
+	case notion.DBPropTypeFormula:
+		if value.Formula != nil && value.Formula.Date != nil {
+			return *value.Formula.Date, true
+		}
+	case notion.DBPropTypeRollup:
+		if value.Rollup != nil && value.Rollup.Date != nil {
+			return *value.Rollup.Date, true
+		}
+	case notion.DBPropTypeCreatedTime:
+		if value.CreatedTime != nil {
+			return notion.Date{Start: notion.NewDateTime(*value.CreatedTime, true)}, true
+		}
+	case notion.DBPropTypeLastEditedTime:
+		if value.LastEditedTime != nil {
+			return notion.Date{Start: notion.NewDateTime(*value.LastEditedTime, true)}, true
+		}
+	}
+
+	return notion.Date{}, false
+}
+
+func (m *migrator) formatDate(date notion.DateTime) string {
+	if date.HasTime() {
+		return m.localTime(date).Format(frontmatter.DateTimeFormat)
+	}
+
+	return date.Time.Format(frontmatter.DateFormat)
+}
+
+// dateProperty returns the value of a Notion date, with time only when the date has one.
+func (m *migrator) dateProperty(date notion.DateTime) any {
+	if date.HasTime() {
+		return m.localTime(date)
+	}
+
+	return frontmatter.Date(date.Time)
+}
+
+// titleDate formats a date for a page name, with the format of the page name or the default date format.
+func (m *migrator) titleDate(date notion.DateTime, format string) string {
+	if format == "" {
+		format = m.dateFormat()
+	}
+
+	return timefmt.Format(m.localTime(date), format)
+}
+
+// dateMention links a date mention to the daily note of the day, keeping the time and the end of ranges:
+// `[[2024-10-10]] 09:30 → [[2024-10-12]] 18:00`.
+func (m *migrator) dateMention(date notion.Date) string {
+	date = m.inTimeZone(date)
+	mention := m.dailyNoteLink(date.Start)
+	if date.End != nil {
+		mention += " → " + m.dailyNoteLink(*date.End)
+	}
+
+	return mention
+}
+
+func (m *migrator) dailyNoteLink(date notion.DateTime) string {
+	t := m.localTime(date)
+	link := fmt.Sprintf("[[%s]]", timefmt.Format(t, m.dateFormat()))
+
+	if date.HasTime() {
+		link += " " + t.Format("15:04")
+	}
+
+	return link
+}
+
+func (m *migrator) dateFormat() string {
+	if m.config.DateFormat == "" {
+		return config.DefaultDateFormat
+	}
+
+	return m.config.DateFormat
+}
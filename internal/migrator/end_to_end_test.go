@@ -0,0 +1,1020 @@
+package migrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/GustavoCaso/n2o/internal/log"
+	"github.com/GustavoCaso/n2o/internal/notiontest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMigrate_EndToEnd migrates workspaces served by a fake Notion API.
+func TestMigrate_EndToEnd(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *config.Config
+		setup    func(srv *notiontest.Server)
+		expected map[string]string
+		// notes is the number of notes expected in the vault, when not every note is listed in expected.
+		notes int
+		// requests is the number of requests expected for some endpoints, for example `GET /v1/pages/<id>`.
+		requests map[string]int
+	}{
+		{
+			name: "nested pages",
+			config: &config.Config{
+				PageID:    "10000000-0000-0000-0000-000000000001",
+				Recursive: true,
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddPage(notiontest.Page{
+					ID:    "10000000-0000-0000-0000-000000000001",
+					Title: "Projects",
+					Content: []notiontest.Block{
+						notiontest.Heading1("Projects"),
+						notiontest.BulletedListItem("Active", notiontest.BulletedListItem("Roadmap")),
+						notiontest.ChildPage("10000000-0000-0000-0000-000000000002", "Roadmap"),
+					},
+				})
+				srv.AddPage(notiontest.Page{
+					ID:     "10000000-0000-0000-0000-000000000002",
+					Title:  "Roadmap",
+					Parent: notiontest.PageParent("10000000-0000-0000-0000-000000000001"),
+					Content: []notiontest.Block{
+						notiontest.Paragraph("Next quarter"),
+						notiontest.ChildPage("10000000-0000-0000-0000-000000000003", "Q1"),
+					},
+				})
+				srv.AddPage(notiontest.Page{
+					ID:     "10000000-0000-0000-0000-000000000003",
+					Title:  "Q1",
+					Parent: notiontest.PageParent("10000000-0000-0000-0000-000000000002"),
+					Content: []notiontest.Block{
+						notiontest.ToDo("Ship it", true),
+					},
+				})
+			},
+			expected: map[string]string{
+				"Projects.md":            "# Projects\n- Active\n\t- Roadmap\n[[Projects/Roadmap.md]]\n",
+				"Projects/Roadmap.md":    "Next quarter\n[[Projects/Roadmap/Q1.md]]\n",
+				"Projects/Roadmap/Q1.md": "- [x] Ship it\n",
+			},
+		},
+		{
+			name: "pages mentioning each other",
+			config: &config.Config{
+				PageID: "20000000-0000-0000-0000-000000000001",
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddPage(notiontest.Page{
+					ID:    "20000000-0000-0000-0000-000000000001",
+					Title: "Ping",
+					Content: []notiontest.Block{
+						notiontest.MentionPage("20000000-0000-0000-0000-000000000002", "Pong"),
+					},
+				})
+				srv.AddPage(notiontest.Page{
+					ID:    "20000000-0000-0000-0000-000000000002",
+					Title: "Pong",
+					Content: []notiontest.Block{
+						notiontest.MentionPage("20000000-0000-0000-0000-000000000001", "Ping"),
+					},
+				})
+			},
+			expected: map[string]string{
+				"Ping.md": "[[Pong.md]]\n",
+				"Pong.md": "[[Ping.md]]\n",
+			},
+		},
+		{
+			name: "database with property mappings",
+			config: &config.Config{
+				DatabaseID:              "50000000-0000-0000-0000-000000000001",
+				PagePropertiesToMigrate: map[string]bool{"all": true},
+				PropertyMappings: map[string]config.PropertyMapping{
+					"area":     {Action: config.PropertyTags, Key: "tags", TagPrefix: "area/"},
+					"labels":   {Action: config.PropertyTags, Key: "tags"},
+					"nickname": {Action: config.PropertyAliases, Key: "aliases"},
+					"tags":     {Action: config.PropertyRename, Key: "notion-tags"},
+					"internal": {Action: config.PropertyDrop},
+				},
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddDatabase(notiontest.Database{ID: "50000000-0000-0000-0000-000000000001", Title: "Notes"})
+				srv.AddPage(notiontest.Page{
+					ID:     "50000000-0000-0000-0000-000000000002",
+					Title:  "Focus",
+					Parent: notiontest.DatabaseParent("50000000-0000-0000-0000-000000000001"),
+					Properties: map[string]any{
+						"Area":     notiontest.SelectProperty("Deep Work"),
+						"Labels":   notiontest.MultiSelectProperty("Reading List", "Q&A", "deep work"),
+						"Nickname": notiontest.TextProperty("Flow: state"),
+						"Tags":     notiontest.TextProperty("kept as text"),
+						"Internal": notiontest.TextProperty("secret"),
+					},
+					Content: []notiontest.Block{notiontest.Paragraph("Notes")},
+				})
+			},
+			expected: map[string]string{
+				"Notes/Focus.md": `---
+tags:
+  - area/deep-work
+  - reading-list
+  - q-a
+  - deep-work
+Name: Focus
+aliases:
+  - 'Flow: state'
+notion-tags: kept as text
+---
+Notes
+`,
+			},
+		},
+		{
+			name: "database with people, files and formulas",
+			config: &config.Config{
+				DatabaseID:              "60000000-0000-0000-0000-000000000001",
+				PagePropertiesToMigrate: map[string]bool{"all": true},
+				StoreImages:             true,
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddUser(notiontest.User{ID: "60000000-0000-0000-0000-0000000000a1", Name: "Ada Lovelace"})
+				srv.AddUser(notiontest.User{ID: "60000000-0000-0000-0000-0000000000a2", Email: "grace@example.com"})
+				srv.AddDatabase(notiontest.Database{ID: "60000000-0000-0000-0000-000000000001", Title: "Reports"})
+				srv.AddPage(notiontest.Page{
+					ID:     "60000000-0000-0000-0000-000000000002",
+					Title:  "Quarterly",
+					Parent: notiontest.DatabaseParent("60000000-0000-0000-0000-000000000001"),
+					Properties: map[string]any{
+						"Owners": notiontest.PeopleProperty(
+							"60000000-0000-0000-0000-0000000000a1",
+							"60000000-0000-0000-0000-0000000000a2",
+						),
+						"Attachments": srv.FilesProperty(
+							srv.AddFile("report.pdf", "report"),
+							srv.AddFile("chart.png", "chart"),
+							"https://example.com/spec.pdf",
+						),
+						"Summary": notiontest.FormulaProperty("Q1: on track"),
+						"Score":   notiontest.FormulaProperty(4.5),
+						"Done":    notiontest.FormulaProperty(true),
+						"Due":     notiontest.FormulaProperty(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)),
+					},
+					Content: []notiontest.Block{notiontest.Paragraph("Numbers")},
+				})
+			},
+			expected: map[string]string{
+				"Reports/Quarterly.md": `---
+Attachments:
+  - '[[Images/Quarterly.md/report.pdf]]'
+  - '[[Images/Quarterly.md/chart.png]]'
+  - https://example.com/spec.pdf
+Done: true
+Due: 2024-03-31
+Name: Quarterly
+Owners:
+  - Ada Lovelace
+  - grace@example.com
+Score: 4.5
+Summary: 'Q1: on track'
+---
+Numbers
+`,
+				"Images/Quarterly.md/report.pdf": "report",
+				"Images/Quarterly.md/chart.png":  "chart",
+			},
+			notes: 1,
+			requests: map[string]int{
+				"GET /v1/users/60000000-0000-0000-0000-0000000000a1": 1,
+				"GET /files/report.pdf":                              1,
+				"GET /files/chart.png":                               1,
+			},
+		},
+		{
+			name: "database with relations and rollups beyond the page limits",
+			config: &config.Config{
+				DatabaseID:              "70000000-0000-0000-0000-000000000001",
+				PagePropertiesToMigrate: map[string]bool{"all": true},
+			},
+			setup: func(srv *notiontest.Server) {
+				relations := []string{}
+				scores := []map[string]any{}
+				text := []any{}
+				for i := range 30 {
+					id := fmt.Sprintf("70000000-0000-0000-0000-%012d", i+100)
+					srv.AddPage(notiontest.Page{ID: id, Title: fmt.Sprintf("Item %d", i)})
+					relations = append(relations, id)
+					scores = append(scores, notiontest.NumberProperty(float64(i)))
+				}
+				for i := range 120 {
+					text = append(text, notiontest.RichText(fmt.Sprintf("%d ", i%10))...)
+				}
+
+				srv.AddDatabase(notiontest.Database{ID: "70000000-0000-0000-0000-000000000001", Title: "Lists"})
+				srv.AddPage(notiontest.Page{
+					ID:     "70000000-0000-0000-0000-000000000002",
+					Title:  "Everything",
+					Parent: notiontest.DatabaseParent("70000000-0000-0000-0000-000000000001"),
+					Properties: map[string]any{
+						"Items":  notiontest.RelationProperty(relations...),
+						"Scores": notiontest.RollupProperty(scores...),
+						"Digits": map[string]any{"type": "rich_text", "rich_text": text},
+					},
+				})
+			},
+			expected: map[string]string{
+				"Lists/Everything.md": fmt.Sprintf("---\nDigits: '%s'\nItems:\n%sName: Everything\nScores:\n%s---\n",
+					strings.Repeat("0 1 2 3 4 5 6 7 8 9 ", 12),
+					listOf(30, func(i int) string { return fmt.Sprintf("'[[Item %d.md]]'", i) }),
+					listOf(30, strconv.Itoa),
+				),
+			},
+			// The related pages are migrated with the page.
+			notes: 31,
+			requests: map[string]int{
+				"GET /v1/pages/70000000-0000-0000-0000-000000000002/properties/Items":  1,
+				"GET /v1/pages/70000000-0000-0000-0000-000000000002/properties/Scores": 1,
+				"GET /v1/pages/70000000-0000-0000-0000-000000000002/properties/Digits": 2,
+			},
+		},
+		{
+			name: "rollup over a truncated relation that is not migrated",
+			config: &config.Config{
+				DatabaseID:              "70000000-0000-0000-0000-000000000003",
+				PagePropertiesToMigrate: map[string]bool{"scores": true},
+			},
+			setup: func(srv *notiontest.Server) {
+				relations := []string{}
+				scores := []map[string]any{}
+				for i := range 30 {
+					relations = append(relations, fmt.Sprintf("70000000-0000-0000-0000-%012d", i+200))
+				}
+				for i := range 3 {
+					scores = append(scores, notiontest.NumberProperty(float64(i)))
+				}
+
+				srv.AddDatabase(notiontest.Database{ID: "70000000-0000-0000-0000-000000000003", Title: "Lists"})
+				srv.AddPage(notiontest.Page{
+					ID:     "70000000-0000-0000-0000-000000000004",
+					Title:  "Scores only",
+					Parent: notiontest.DatabaseParent("70000000-0000-0000-0000-000000000003"),
+					Properties: map[string]any{
+						"Items":  notiontest.RelationProperty(relations...),
+						"Scores": notiontest.RollupProperty(scores...),
+					},
+				})
+			},
+			expected: map[string]string{
+				"Lists/Scores only.md": "---\nScores:\n  - 0\n  - 1\n  - 2\n---\n",
+			},
+			requests: map[string]int{
+				"GET /v1/pages/70000000-0000-0000-0000-000000000004/properties/Items":  0,
+				"GET /v1/pages/70000000-0000-0000-0000-000000000004/properties/Scores": 1,
+			},
+		},
+		{
+			name: "database with rollups of every type",
+			config: &config.Config{
+				DatabaseID:              "80000000-0000-0000-0000-000000000001",
+				PagePropertiesToMigrate: map[string]bool{"all": true},
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddUser(notiontest.User{ID: "80000000-0000-0000-0000-0000000000a1", Name: "Ada Lovelace"})
+				srv.AddPage(notiontest.Page{ID: "80000000-0000-0000-0000-000000000003", Title: "Alpha"})
+				srv.AddPage(notiontest.Page{ID: "80000000-0000-0000-0000-000000000004", Title: "Beta"})
+				srv.AddDatabase(notiontest.Database{ID: "80000000-0000-0000-0000-000000000001", Title: "Rollups"})
+				srv.AddPage(notiontest.Page{
+					ID:     "80000000-0000-0000-0000-000000000002",
+					Title:  "Summary",
+					Parent: notiontest.DatabaseParent("80000000-0000-0000-0000-000000000001"),
+					Properties: map[string]any{
+						"Projects": notiontest.RollupProperty(
+							notiontest.RelationProperty("80000000-0000-0000-0000-000000000003"),
+							notiontest.RelationProperty(
+								"80000000-0000-0000-0000-000000000004",
+								"80000000-0000-0000-0000-000000000003",
+							),
+						),
+						"Titles": notiontest.RollupProperty(
+							map[string]any{"type": "title", "title": notiontest.RichText("Plan: draft")},
+							map[string]any{"type": "title", "title": notiontest.RichText("")},
+						),
+						"Stages": notiontest.RollupProperty(
+							notiontest.SelectProperty("Done"),
+							notiontest.MultiSelectProperty("Open", "Blocked"),
+						),
+						"Dates": notiontest.RollupProperty(
+							map[string]any{"type": "date", "date": map[string]any{"start": "2024-01-01", "end": nil}},
+							map[string]any{"type": "date", "date": map[string]any{"start": "2024-02-01", "end": "2024-02-03"}},
+						),
+						"Owners": notiontest.RollupProperty(notiontest.PeopleProperty("80000000-0000-0000-0000-0000000000a1")),
+						"Complete": notiontest.RollupProperty(
+							map[string]any{"type": "checkbox", "checkbox": true},
+							map[string]any{"type": "checkbox", "checkbox": false},
+						),
+						"Period": map[string]any{
+							"type": "rollup",
+							"rollup": map[string]any{
+								"type":     "date",
+								"date":     map[string]any{"start": "2024-01-01T09:00:00.000Z", "end": "2024-01-05T18:00:00.000Z"},
+								"function": "date_range",
+							},
+						},
+					},
+				})
+			},
+			expected: map[string]string{
+				"Rollups/Summary.md": `---
+Complete:
+  - true
+  - false
+Dates:
+  - 2024-01-01
+  - 2024-02-01/2024-02-03
+Name: Summary
+Owners:
+  - Ada Lovelace
+Period: 2024-01-01T09:00:00Z/2024-01-05T18:00:00Z
+Projects:
+  - '[[Alpha.md]]'
+  - '[[Beta.md]]'
+  - '[[Alpha.md]]'
+Stages:
+  - Done
+  - Open
+  - Blocked
+Titles:
+  - 'Plan: draft'
+---
+`,
+			},
+			// The related pages are migrated with the page.
+			notes: 3,
+		},
+		{
+			name: "database with date ranges and time zones",
+			config: &config.Config{
+				DatabaseID:              "90000000-0000-0000-0000-000000000001",
+				PagePropertiesToMigrate: map[string]bool{"all": true},
+				PageNameFilters:         map[string]string{"when": ""},
+				DateFormat:              "%Y/%m/%d",
+				DateRange:               config.DateRangeSplit,
+				TimeZone:                mustLoadLocation("Europe/Madrid"),
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddDatabase(notiontest.Database{ID: "90000000-0000-0000-0000-000000000001", Title: "Events"})
+				srv.AddPage(notiontest.Page{
+					ID:     "90000000-0000-0000-0000-000000000002",
+					Title:  "Offsite",
+					Parent: notiontest.DatabaseParent("90000000-0000-0000-0000-000000000001"),
+					Properties: map[string]any{
+						"When":     notiontest.DateProperty("2024-10-09T23:30:00.000+00:00", "2024-10-11T16:00:00.000+00:00"),
+						"Deadline": notiontest.DateProperty("2024-09-30", ""),
+						"Span": map[string]any{
+							"type": "formula",
+							"formula": map[string]any{
+								"type": "date",
+								"date": notiontest.DateProperty("2024-10-01T08:00:00.000+00:00", "2024-10-02T08:00:00.000+00:00")["date"],
+							},
+						},
+						"Created": map[string]any{"type": "created_time", "created_time": "2024-09-01T22:15:00.000Z"},
+					},
+					Content: []notiontest.Block{
+						notiontest.MentionDate("2024-10-09T23:30:00.000+00:00", ""),
+						notiontest.MentionDate("2024-10-10", "2024-10-12"),
+					},
+				})
+			},
+			expected: map[string]string{
+				"Events/2024/10/10.md": `---
+Created_start: 2024-09-02T00:15:00+02:00
+Deadline_start: 2024-09-30
+Name: Offsite
+Span_start: 2024-10-01T10:00:00+02:00
+Span_end: 2024-10-02T10:00:00+02:00
+When_start: 2024-10-10T01:30:00+02:00
+When_end: 2024-10-11T18:00:00+02:00
+---
+[[2024/10/10]] 01:30
+[[2024/10/10]] → [[2024/10/12]]
+`,
+			},
+		},
+		{
+			name: "database with dates in the time zone returned by Notion",
+			config: &config.Config{
+				DatabaseID:              "90000000-0000-0000-0000-000000000003",
+				PagePropertiesToMigrate: map[string]bool{"all": true},
+			},
+			setup: func(srv *notiontest.Server) {
+				when := notiontest.DateProperty("2024-10-10T13:30:00.000+00:00", "")
+				when["date"].(map[string]any)["time_zone"] = "America/New_York"
+
+				srv.AddDatabase(notiontest.Database{ID: "90000000-0000-0000-0000-000000000003", Title: "Events"})
+				srv.AddPage(notiontest.Page{
+					ID:         "90000000-0000-0000-0000-000000000004",
+					Title:      "Call",
+					Parent:     notiontest.DatabaseParent("90000000-0000-0000-0000-000000000003"),
+					Properties: map[string]any{"When": when},
+				})
+			},
+			expected: map[string]string{
+				"Events/Call.md": "---\nName: Call\nWhen: 2024-10-10T09:30:00-04:00\n---\n",
+			},
+		},
+		{
+			name: "database with a page name template",
+			config: &config.Config{
+				DatabaseID:       "a0000000-0000-0000-0000-000000000001",
+				PageNameTemplate: `{{.Status | lower}}/{{date .Due "%Y-%m"}} - {{.Name | truncate 9}}`,
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddDatabase(notiontest.Database{
+					ID:    "a0000000-0000-0000-0000-000000000001",
+					Title: "Tasks",
+					Properties: map[string]any{
+						"Status": map[string]any{"id": "status", "name": "Status", "type": "select", "select": map[string]any{}},
+						"Due":    map[string]any{"id": "due", "name": "Due", "type": "date", "date": map[string]any{}},
+					},
+				})
+				srv.AddPage(notiontest.Page{
+					ID:     "a0000000-0000-0000-0000-000000000002",
+					Title:  "Write the quarterly report",
+					Parent: notiontest.DatabaseParent("a0000000-0000-0000-0000-000000000001"),
+					Properties: map[string]any{
+						"Status": notiontest.SelectProperty("Done"),
+						"Due":    notiontest.DateProperty("2024-03-31", ""),
+					},
+					Content: []notiontest.Block{notiontest.Paragraph("Numbers")},
+				})
+			},
+			expected: map[string]string{
+				"Tasks/done/2024-03 - Write the.md": "Numbers\n",
+			},
+		},
+		{
+			name: "database with duplicated titles and titles not allowed in file names",
+			config: &config.Config{
+				DatabaseID: "b0000000-0000-0000-0000-000000000001",
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddDatabase(notiontest.Database{ID: "b0000000-0000-0000-0000-000000000001", Title: "Meetings"})
+				// Notion returns the newest page first, the oldest one keeps the name.
+				srv.AddPage(notiontest.Page{
+					ID:             "b0000000-0000-0000-0000-000000000003",
+					Title:          "Weekly: sync",
+					Parent:         notiontest.DatabaseParent("b0000000-0000-0000-0000-000000000001"),
+					LastEditedTime: time.Date(2024, 10, 17, 10, 0, 0, 0, time.UTC),
+					Content:        []notiontest.Block{notiontest.Paragraph("Second week")},
+				})
+				srv.AddPage(notiontest.Page{
+					ID:             "b0000000-0000-0000-0000-000000000002",
+					Title:          "Weekly: sync",
+					Parent:         notiontest.DatabaseParent("b0000000-0000-0000-0000-000000000001"),
+					LastEditedTime: time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC),
+					Content:        []notiontest.Block{notiontest.Paragraph("First week")},
+				})
+				srv.AddPage(notiontest.Page{
+					ID:     "b0000000-0000-0000-0000-000000000004",
+					Title:  "Client/Server?",
+					Parent: notiontest.DatabaseParent("b0000000-0000-0000-0000-000000000001"),
+					Content: []notiontest.Block{
+						notiontest.MentionPage("b0000000-0000-0000-0000-000000000003", "Weekly: sync"),
+						notiontest.MentionPage("b0000000-0000-0000-0000-000000000005", "Weekly: sync"),
+					},
+				})
+				srv.AddPage(notiontest.Page{
+					ID:      "b0000000-0000-0000-0000-000000000005",
+					Title:   "Weekly: sync",
+					Content: []notiontest.Block{notiontest.Paragraph("Outside the database")},
+				})
+			},
+			expected: map[string]string{
+				"Meetings/Weekly- sync.md":            "First week\n",
+				"Meetings/Weekly- sync (00000003).md": "Second week\n",
+				"Meetings/Client-Server.md":           "[[Weekly- sync (00000003).md]]\n[[Weekly- sync.md]]\n",
+				"Weekly- sync.md":                     "Outside the database\n",
+			},
+		},
+		{
+			name: "large database",
+			config: &config.Config{
+				DatabaseID: "30000000-0000-0000-0000-000000000001",
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddDatabase(notiontest.Database{ID: "30000000-0000-0000-0000-000000000001", Title: "Tasks"})
+				for i := range 250 {
+					srv.AddPage(notiontest.Page{
+						ID:     fmt.Sprintf("30000000-0000-0000-0000-%012d", i+2),
+						Title:  fmt.Sprintf("Task %d", i),
+						Parent: notiontest.DatabaseParent("30000000-0000-0000-0000-000000000001"),
+						Content: []notiontest.Block{
+							notiontest.Paragraph(fmt.Sprintf("Task number %d", i)),
+						},
+					})
+				}
+			},
+			expected: map[string]string{
+				"Tasks/Task 0.md":   "Task number 0\n",
+				"Tasks/Task 249.md": "Task number 249\n",
+			},
+			notes: 250,
+			requests: map[string]int{
+				"POST /v1/databases/30000000-0000-0000-0000-000000000001/query": 3,
+			},
+		},
+		{
+			name: "numbered lists",
+			config: &config.Config{
+				PageID: "d0000000-0000-0000-0000-000000000001",
+			},
+			setup: func(srv *notiontest.Server) {
+				rollBack := notiontest.NumberedListItem("Roll back")
+				rollBack.Data["list_start_index"] = 5
+
+				srv.AddPage(notiontest.Page{
+					ID:    "d0000000-0000-0000-0000-000000000001",
+					Title: "Runbook",
+					Content: []notiontest.Block{
+						notiontest.NumberedListItem(
+							"Install",
+							notiontest.NumberedListItem("Download"),
+							notiontest.NumberedListItem("Unpack", notiontest.NumberedListItem("Check the hash")),
+						),
+						notiontest.NumberedListItem("Configure"),
+						notiontest.NumberedListItem("Start"),
+						notiontest.Paragraph("Then"),
+						notiontest.NumberedListItem("Verify"),
+						notiontest.BulletedListItem("Note"),
+						notiontest.NumberedListItem("Clean up"),
+						notiontest.Paragraph("Otherwise"),
+						rollBack,
+						notiontest.NumberedListItem("Report"),
+					},
+				})
+			},
+			expected: map[string]string{
+				"Runbook.md": "1. Install\n\t1. Download\n\t2. Unpack\n\t\t1. Check the hash\n2. Configure\n3. Start\n" +
+					"Then\n1. Verify\n- Note\n1. Clean up\nOtherwise\n5. Roll back\n6. Report\n",
+			},
+		},
+		{
+			name: "callouts",
+			config: &config.Config{
+				PageID:       "e0000000-0000-0000-0000-000000000001",
+				CalloutTypes: map[string]string{"🔥": "danger", "gray": "abstract"},
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddPage(notiontest.Page{
+					ID:    "e0000000-0000-0000-0000-000000000001",
+					Title: "Guide",
+					Content: []notiontest.Block{
+						notiontest.Callout(notiontest.EmojiIcon("💡"), "default", "Use the CLI"),
+						notiontest.Callout(
+							notiontest.EmojiIcon("⚠"),
+							"default",
+							"Back up first",
+							notiontest.Paragraph("Run the backup"),
+							notiontest.BulletedListItem("Check it", notiontest.BulletedListItem("Twice")),
+						),
+						notiontest.Callout(notiontest.EmojiIcon("🎨"), "blue_background", "Colors"),
+						notiontest.Callout(notiontest.EmojiIcon("🥑"), "default", "Unknown emoji"),
+						notiontest.Callout(notiontest.ExternalIcon("https://example.com/icon.png"), "red", "External icon"),
+						notiontest.Callout(nil, "gray_background", "No icon"),
+						notiontest.Callout(notiontest.EmojiIcon("🔥"), "default", "Configured"),
+						notiontest.Callout(notiontest.EmojiIcon("📝"), "default", ""),
+					},
+				})
+			},
+			expected: map[string]string{
+				"Guide.md": "> [!tip]\n> Use the CLI\n\n" +
+					"> [!warning]\n> Back up first\n> Run the backup\n> - Check it\n> \t- Twice\n\n" +
+					"> [!info] 🎨\n> Colors\n\n" +
+					"> [!note] 🥑\n> Unknown emoji\n\n" +
+					"> [!danger]\n> External icon\n\n" +
+					"> [!abstract]\n> No icon\n\n" +
+					"> [!danger]\n> Configured\n\n" +
+					"> [!note]\n\n",
+			},
+		},
+		{
+			name: "toggles as callouts",
+			config: &config.Config{
+				PageID:  "f0000000-0000-0000-0000-000000000001",
+				Toggles: config.TogglesCallout,
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddPage(notiontest.Page{
+					ID:    "f0000000-0000-0000-0000-000000000001",
+					Title: "FAQ",
+					Content: []notiontest.Block{
+						notiontest.Toggle(
+							"How do I sign in?",
+							notiontest.Paragraph("Use your SSO account"),
+							notiontest.Toggle("And without SSO?", notiontest.BulletedListItem("Ask IT")),
+						),
+						notiontest.ToggleHeading(2, "Billing", notiontest.Paragraph("Invoices are monthly")),
+						notiontest.Heading2("Contact"),
+					},
+				})
+			},
+			expected: map[string]string{
+				"FAQ.md": "> [!note]- How do I sign in?\n> Use your SSO account\n" +
+					"> > [!note]- And without SSO?\n> > - Ask IT\n>\n\n" +
+					"## Billing\nInvoices are monthly\n" +
+					"## Contact\n",
+			},
+		},
+		{
+			name: "toggles as details",
+			config: &config.Config{
+				PageID:  "f0000000-0000-0000-0000-000000000002",
+				Toggles: config.TogglesDetails,
+			},
+			setup: func(srv *notiontest.Server) {
+				question := notiontest.Toggle("Is <b> allowed?", notiontest.Paragraph("No"))
+				bold := question.Data["rich_text"].([]any)[0].(map[string]any)["annotations"].(map[string]any)
+				bold["bold"] = true
+
+				srv.AddPage(notiontest.Page{
+					ID:    "f0000000-0000-0000-0000-000000000002",
+					Title: "FAQ",
+					Content: []notiontest.Block{
+						question,
+						notiontest.ToggleHeading(1, "Billing", notiontest.BulletedListItem("Monthly")),
+					},
+				})
+			},
+			expected: map[string]string{
+				"FAQ.md": "<details>\n<summary>Is &lt;b&gt; allowed?</summary>\n\nNo\n\n</details>\n" +
+					"# Billing\n- Monthly\n",
+			},
+		},
+		{
+			name: "toggles as lists",
+			config: &config.Config{
+				PageID: "f0000000-0000-0000-0000-000000000003",
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddPage(notiontest.Page{
+					ID:    "f0000000-0000-0000-0000-000000000003",
+					Title: "FAQ",
+					Content: []notiontest.Block{
+						notiontest.Toggle("Question", notiontest.Paragraph("Answer")),
+						notiontest.ToggleHeading(3, "Billing", notiontest.Paragraph("Monthly")),
+					},
+				})
+			},
+			expected: map[string]string{
+				"FAQ.md": "- Question\n\tAnswer\n### Billing\n\tMonthly\n",
+			},
+		},
+		{
+			name: "synced blocks",
+			config: &config.Config{
+				DatabaseID: "a1000000-0000-0000-0000-000000000001",
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddDatabase(notiontest.Database{ID: "a1000000-0000-0000-0000-000000000001", Title: "Docs"})
+				srv.AddPage(notiontest.Page{
+					ID:     "a1000000-0000-0000-0000-000000000002",
+					Title:  "Policies",
+					Parent: notiontest.DatabaseParent("a1000000-0000-0000-0000-000000000001"),
+					Content: []notiontest.Block{
+						notiontest.Paragraph("Intro"),
+						notiontest.SyncedBlock(
+							"a1000000-0000-0000-0000-0000000000b1",
+							notiontest.Heading3("Rules"),
+							notiontest.Paragraph("Be kind"),
+							notiontest.BulletedListItem("Be on time"),
+						),
+						notiontest.Paragraph("Outro"),
+					},
+				})
+				srv.AddPage(notiontest.Page{
+					ID:     "a1000000-0000-0000-0000-000000000003",
+					Title:  "Onboarding",
+					Parent: notiontest.DatabaseParent("a1000000-0000-0000-0000-000000000001"),
+					Content: []notiontest.Block{
+						notiontest.BulletedListItem(
+							"Rules",
+							notiontest.SyncedBlockCopy("a1000000-0000-0000-0000-0000000000b1"),
+						),
+						notiontest.SyncedBlockCopy("a1000000-0000-0000-0000-0000000000b2"),
+						notiontest.SyncedBlockCopy("a1000000-0000-0000-0000-0000000000b3"),
+						notiontest.SyncedBlockCopy("a1000000-0000-0000-0000-0000000000b4"),
+						notiontest.MentionPage("a1000000-0000-0000-0000-000000000005", "Glossary"),
+					},
+				})
+				// The page of the original of the last duplicate is only migrated after it, from the mention.
+				srv.AddPage(notiontest.Page{
+					ID:    "a1000000-0000-0000-0000-000000000005",
+					Title: "Glossary",
+					Content: []notiontest.Block{
+						notiontest.SyncedBlock("a1000000-0000-0000-0000-0000000000b4", notiontest.Paragraph("Sync: a copy")),
+					},
+				})
+				// The original of the second duplicate is on a page outside the database.
+				srv.AddPage(notiontest.Page{
+					ID:    "a1000000-0000-0000-0000-000000000004",
+					Title: "Templates",
+					Content: []notiontest.Block{
+						notiontest.Toggle(
+							"Footer",
+							notiontest.SyncedBlock(
+								"a1000000-0000-0000-0000-0000000000b2",
+								notiontest.Paragraph("Questions? Ask in #help"),
+							),
+						),
+					},
+				})
+			},
+			expected: map[string]string{
+				"Docs/Policies.md": "Intro\n\n### Rules\nBe kind\n- Be on time\n\n" +
+					"^a10000000000000000000000000000b1\n\nOutro\n",
+				"Docs/Onboarding.md": "- Rules\n\t![[Policies.md#^a10000000000000000000000000000b1]]\n" +
+					"Questions? Ask in #help\n" +
+					"> [!warning] Synced block a1000000-0000-0000-0000-0000000000b3 not found\n\n" +
+					"![[Glossary.md#^a10000000000000000000000000000b4]]\n" +
+					"[[Glossary.md]]\n",
+				"Glossary.md": "\nSync: a copy\n\n^a10000000000000000000000000000b4\n\n",
+			},
+		},
+		{
+			name: "workspace with a child database",
+			config: &config.Config{
+				Workspace: true,
+				Recursive: true,
+			},
+			setup: func(srv *notiontest.Server) {
+				srv.AddPage(notiontest.Page{
+					ID:    "d1000000-0000-0000-0000-000000000001",
+					Title: "Home",
+					Content: []notiontest.Block{
+						notiontest.ChildDatabase("d1000000-0000-0000-0000-000000000002", "Tasks: Q1"),
+					},
+				})
+				srv.AddDatabase(notiontest.Database{
+					ID:     "d1000000-0000-0000-0000-000000000002",
+					Title:  "Tasks: Q1",
+					Parent: notiontest.PageParent("d1000000-0000-0000-0000-000000000001"),
+				})
+				srv.AddPage(notiontest.Page{
+					ID:      "d1000000-0000-0000-0000-000000000003",
+					Title:   "Write docs",
+					Parent:  notiontest.DatabaseParent("d1000000-0000-0000-0000-000000000002"),
+					Cover:   srv.AddFile("cover.png", "cover"),
+					Content: []notiontest.Block{notiontest.Paragraph("Draft")},
+				})
+			},
+			expected: map[string]string{
+				"Home.md": "Tasks- Q1\n- [[Home/Tasks- Q1/Write docs.md]]\n",
+			},
+			notes: 2,
+			// The search already listed the pages of the database.
+			requests: map[string]int{
+				"GET /v1/databases/d1000000-0000-0000-0000-000000000002":        0,
+				"POST /v1/databases/d1000000-0000-0000-0000-000000000002/query": 0,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			srv := notiontest.NewServer(t)
+			test.setup(srv)
+
+			test.config.VaultPath = t.TempDir()
+
+			logger, _ := log.MockLogger()
+			m, err := NewMigrator(test.config, NewCache(), logger, WithNotionHTTPClient(srv.Client()))
+			require.NoError(t, err)
+
+			ctx := context.TODO()
+
+			pages, err := m.FetchPages(ctx)
+			require.NoError(t, err)
+
+			for _, page := range pages {
+				require.NoError(t, m.FetchParseAndSavePage(ctx, page, test.config.PagePropertiesToMigrate))
+			}
+
+			require.NoError(t, m.WritePagesToDisk(ctx))
+
+			for notePath, expected := range test.expected {
+				content, err := os.ReadFile(filepath.Join(test.config.VaultPath, notePath))
+				require.NoError(t, err)
+				assert.Equal(t, expected, string(content), notePath)
+			}
+
+			notes := 0
+			require.NoError(t, filepath.WalkDir(test.config.VaultPath, func(_ string, entry os.DirEntry, err error) error {
+				if err == nil && !entry.IsDir() && filepath.Ext(entry.Name()) == ".md" {
+					notes++
+				}
+				return err
+			}))
+			if test.notes == 0 {
+				test.notes = len(test.expected)
+			}
+			assert.Equal(t, test.notes, notes)
+
+			for request, expected := range test.requests {
+				assert.Equal(t, expected, srv.Requests(request), request)
+			}
+		})
+	}
+}
+
+// listOf returns a YAML list of n items.
+func listOf(n int, item func(i int) string) string {
+	list := &strings.Builder{}
+	for i := range n {
+		fmt.Fprintf(list, "  - %s\n", item(i))
+	}
+
+	return list.String()
+}
+
+func TestMigrate_SyncedBlockOfFailedPage(t *testing.T) {
+	srv := notiontest.NewServer(t)
+	srv.AddDatabase(notiontest.Database{ID: "a2000000-0000-0000-0000-000000000001", Title: "Docs"})
+	srv.AddPage(notiontest.Page{
+		ID:     "a2000000-0000-0000-0000-000000000002",
+		Title:  "Policies",
+		Parent: notiontest.DatabaseParent("a2000000-0000-0000-0000-000000000001"),
+		Content: []notiontest.Block{
+			notiontest.SyncedBlock("a2000000-0000-0000-0000-0000000000b1", notiontest.Paragraph("Be kind")),
+			notiontest.MentionPage("a2000000-0000-0000-0000-000000000009", "Missing"),
+		},
+	})
+	srv.AddPage(notiontest.Page{
+		ID:      "a2000000-0000-0000-0000-000000000003",
+		Title:   "Onboarding",
+		Parent:  notiontest.DatabaseParent("a2000000-0000-0000-0000-000000000001"),
+		Content: []notiontest.Block{notiontest.SyncedBlockCopy("a2000000-0000-0000-0000-0000000000b1")},
+	})
+
+	vault := t.TempDir()
+	logger, _ := log.MockLogger()
+	m, err := NewMigrator(&config.Config{
+		DatabaseID: "a2000000-0000-0000-0000-000000000001",
+		VaultPath:  vault,
+	}, NewCache(), logger, WithNotionHTTPClient(srv.Client()))
+	require.NoError(t, err)
+
+	ctx := context.TODO()
+
+	pages, err := m.FetchPages(ctx)
+	require.NoError(t, err)
+
+	errs := []error{}
+	for _, page := range pages {
+		if err = m.FetchParseAndSavePage(ctx, page, map[string]bool{}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	assert.Len(t, errs, 1)
+
+	require.NoError(t, m.WritePagesToDisk(ctx))
+
+	// The note of the original is not written, the duplicate keeps a copy of its content.
+	assertNoFile(t, filepath.Join(vault, "Docs/Policies.md"))
+	assertFileContent(t, filepath.Join(vault, "Docs/Onboarding.md"), "Be kind\n")
+}
+
+func TestMigrate_PageNameTemplateUnknownProperty(t *testing.T) {
+	srv := notiontest.NewServer(t)
+	srv.AddDatabase(notiontest.Database{ID: "a0000000-0000-0000-0000-000000000003", Title: "Tasks"})
+
+	logger, _ := log.MockLogger()
+	m, err := NewMigrator(&config.Config{
+		DatabaseID:       "a0000000-0000-0000-0000-000000000003",
+		PageNameTemplate: "{{.Status}} - {{.Name}}",
+		VaultPath:        t.TempDir(),
+	}, NewCache(), logger, WithNotionHTTPClient(srv.Client()))
+	require.NoError(t, err)
+
+	_, err = m.FetchPages(context.TODO())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "the page name template uses unknown properties Status")
+	assert.Equal(t, 0, srv.Requests("POST /v1/databases/a0000000-0000-0000-0000-000000000003/query"))
+}
+
+func TestMigrate_DatabaseQuery(t *testing.T) {
+	srv := notiontest.NewServer(t)
+	srv.PageSize = 1
+	srv.AddDatabase(notiontest.Database{
+		ID:    "c0000000-0000-0000-0000-000000000001",
+		Title: "Tasks",
+		Properties: map[string]any{
+			"Status": map[string]any{"id": "status", "name": "Status", "type": "status", "status": map[string]any{}},
+			"Due":    map[string]any{"id": "due", "name": "Due", "type": "date", "date": map[string]any{}},
+		},
+	})
+	for _, id := range []string{"c0000000-0000-0000-0000-000000000002", "c0000000-0000-0000-0000-000000000003"} {
+		srv.AddPage(notiontest.Page{
+			ID:     id,
+			Title:  id,
+			Parent: notiontest.DatabaseParent("c0000000-0000-0000-0000-000000000001"),
+		})
+	}
+
+	logger, _ := log.MockLogger()
+	m, err := NewMigrator(&config.Config{
+		DatabaseID: "c0000000-0000-0000-0000-000000000001",
+		Filter:     "Status = Done AND due >= 2024-01-01",
+		Sorts:      "Due desc",
+		VaultPath:  t.TempDir(),
+	}, NewCache(), logger, WithNotionHTTPClient(srv.Client()))
+	require.NoError(t, err)
+
+	pages, err := m.FetchPages(context.TODO())
+	require.NoError(t, err)
+	assert.Len(t, pages, 2)
+
+	// The next pages are requested with the same filter and sorts.
+	queries := srv.Queries("c0000000-0000-0000-0000-000000000001")
+	require.Len(t, queries, 2)
+	for _, query := range queries {
+		filter, err := json.Marshal(query["filter"])
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"and":[
+			{"property":"Status","status":{"equals":"Done"}},
+			{"property":"Due","date":{"on_or_after":"2024-01-01T00:00:00Z"}}
+		]}`, string(filter))
+
+		sorts, err := json.Marshal(query["sorts"])
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"property":"Due","direction":"descending"}]`, string(sorts))
+	}
+}
+
+func TestMigrate_DatabaseQueryUnknownProperty(t *testing.T) {
+	srv := notiontest.NewServer(t)
+	srv.AddDatabase(notiontest.Database{ID: "c0000000-0000-0000-0000-000000000004", Title: "Tasks"})
+
+	logger, _ := log.MockLogger()
+	m, err := NewMigrator(&config.Config{
+		DatabaseID: "c0000000-0000-0000-0000-000000000004",
+		Filter:     "Status = Done",
+		VaultPath:  t.TempDir(),
+	}, NewCache(), logger, WithNotionHTTPClient(srv.Client()))
+	require.NoError(t, err)
+
+	_, err = m.FetchPages(context.TODO())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown property Status. available properties: Name")
+	assert.Empty(t, srv.Queries("c0000000-0000-0000-0000-000000000004"))
+}
+
+func TestNewMigrator_InvalidFilter(t *testing.T) {
+	logger, _ := log.MockLogger()
+	_, err := NewMigrator(&config.Config{
+		DatabaseID: "c0000000-0000-0000-0000-000000000005",
+		Filter:     "Status = ",
+	}, NewCache(), logger)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing value after Status =")
+}
+
+func TestMigrate_NotionBaseURL(t *testing.T) {
+	srv := notiontest.NewServer(t)
+	srv.AddPage(notiontest.Page{
+		ID:      "40000000-0000-0000-0000-000000000001",
+		Title:   "Stand-in",
+		Content: []notiontest.Block{notiontest.Paragraph("Served locally")},
+	})
+
+	config := &config.Config{
+		PageID:        "40000000-0000-0000-0000-000000000001",
+		VaultPath:     t.TempDir(),
+		NotionBaseURL: srv.URL + "/v1",
+	}
+
+	logger, _ := log.MockLogger()
+	m, err := NewMigrator(config, NewCache(), logger)
+	require.NoError(t, err)
+
+	ctx := context.TODO()
+
+	pages, err := m.FetchPages(ctx)
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	require.NoError(t, m.FetchParseAndSavePage(ctx, pages[0], config.PagePropertiesToMigrate))
+	require.NoError(t, m.WritePagesToDisk(ctx))
+
+	assertFileContent(t, filepath.Join(config.VaultPath, "Stand-in.md"), "Served locally\n")
+	assert.Equal(t, 1, srv.Requests("GET /v1/pages/40000000-0000-0000-0000-000000000001"))
+}
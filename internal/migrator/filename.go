@@ -0,0 +1,143 @@
+package migrator
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/dstotijn/go-notion"
+)
+
+// shortIDLength is the number of characters of the page ID added to duplicated note names.
+const shortIDLength = 8
+
+var repeatedSpaces = regexp.MustCompile(` {2,}`)
+
+// newFilenameReplacer returns the replacer for the note and folder names, the configured replacements
+// override the default ones. Longer texts are replaced first.
+func newFilenameReplacer(replacements map[string]string) *strings.Replacer {
+	rules := map[string]string{}
+	for from, to := range config.DefaultFilenameReplacements {
+		rules[from] = to
+	}
+	for from, to := range replacements {
+		rules[from] = to
+	}
+
+	texts := make([]string, 0, len(rules))
+	for from := range rules {
+		texts = append(texts, from)
+	}
+	sort.Slice(texts, func(i, j int) bool {
+		if len(texts[i]) != len(texts[j]) {
+			return len(texts[i]) > len(texts[j])
+		}
+		return texts[i] < texts[j]
+	})
+
+	pairs := make([]string, 0, len(texts)*2)
+	for _, from := range texts {
+		pairs = append(pairs, from, rules[from])
+	}
+
+	return strings.NewReplacer(pairs...)
+}
+
+// sanitizeName turns a Notion title into a note or folder name. Slashes are replaced, they would create folders.
+func (m *migrator) sanitizeName(name string) string {
+	m.filenameReplacerOnce.Do(func() {
+		m.filenameReplacer = newFilenameReplacer(m.config.FilenameReplacements)
+	})
+	name = m.filenameReplacer.Replace(name)
+
+	// Control characters, like tabs and new lines, separate words.
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, name)
+	name = repeatedSpaces.ReplaceAllString(name, " ")
+
+	// Windows does not allow names ending with a dot or a space.
+	name = strings.TrimRight(strings.TrimSpace(name), ". ")
+
+	return truncateName(name, m.maxFilenameLength())
+}
+
+// sanitizePageName sanitizes every folder of a page name, the slashes added by the page name format are kept.
+// The page names always end with `.md`.
+func (m *migrator) sanitizePageName(pageName string) string {
+	segments := []string{}
+	for _, segment := range strings.Split(strings.TrimSuffix(pageName, ".md"), "/") {
+		if segment = m.sanitizeName(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	if len(segments) == 0 {
+		return "untitled.md"
+	}
+
+	return path.Join(segments...) + ".md"
+}
+
+func (m *migrator) maxFilenameLength() int {
+	if m.config.MaxFilenameLength > 0 {
+		return m.config.MaxFilenameLength
+	}
+
+	return config.DefaultMaxFilenameLength
+}
+
+// truncateName cuts a name to length bytes, without splitting a character.
+func truncateName(name string, length int) string {
+	if len(name) <= length {
+		return name
+	}
+
+	name = name[:length]
+	for !utf8.ValidString(name) {
+		name = name[:len(name)-1]
+	}
+
+	return strings.TrimRight(strings.TrimSpace(name), ". ")
+}
+
+// uniquePath returns the path of a page note, adding the short page ID to the name when another page
+// already has the same path. The paths are compared ignoring the case, like macOS and Windows do.
+// The name is cut to leave room for the page ID, the note name stays within the maximum length.
+func (m *migrator) uniquePath(pageID, pagePath string) string {
+	return m.cache.Claim(pageID, pagePath, func(attempt int) string {
+		id := strings.ReplaceAll(pageID, "-", "")
+		if attempt == 1 && len(id) > shortIDLength {
+			id = id[len(id)-shortIDLength:]
+		}
+		suffix := " (" + id + ")"
+
+		folder, name := path.Split(strings.TrimSuffix(pagePath, ".md"))
+		name = truncateName(name, max(m.maxFilenameLength()-len(suffix), 0))
+
+		return folder + name + suffix + ".md"
+	})
+}
+
+// creationOrder returns the pages sorted by creation time, so the oldest page keeps its name
+// when several pages have the same name, whatever order Notion returns them in.
+func creationOrder(pages []notion.Page) []notion.Page {
+	sorted := make([]notion.Page, len(pages))
+	copy(sorted, pages)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedTime.Equal(sorted[j].CreatedTime) {
+			return sorted[i].CreatedTime.Before(sorted[j].CreatedTime)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	return sorted
+}
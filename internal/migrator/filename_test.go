@@ -0,0 +1,111 @@
+package migrator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/dstotijn/go-notion"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *config.Config
+		title    string
+		expected string
+	}{
+		{name: "plain title", config: &config.Config{}, title: "Roadmap", expected: "Roadmap"},
+		{name: "slashes", config: &config.Config{}, title: "Client/Server", expected: "Client-Server"},
+		{name: "characters not allowed on Windows", config: &config.Config{}, title: `a:b*c?d"e<f>g|h\i`, expected: "a-bcdefg-h-i"},
+		{name: "wikilink characters", config: &config.Config{}, title: "#1 [draft] ^ref", expected: "1 (draft) ref"},
+		{name: "control characters", config: &config.Config{}, title: "tab\there\n\nnewline", expected: "tab here newline"},
+		{name: "trailing dots and spaces", config: &config.Config{}, title: "  Wait... ", expected: "Wait"},
+		{name: "only forbidden characters", config: &config.Config{}, title: "???", expected: ""},
+		{
+			name:     "configured replacements",
+			config:   &config.Config{FilenameReplacements: map[string]string{":": " -", "&": "and", "[": "["}},
+			title:    "Q&A: [draft]",
+			expected: "QandA - [draft)",
+		},
+		{
+			name:     "length cap",
+			config:   &config.Config{MaxFilenameLength: 10},
+			title:    "A very long title",
+			expected: "A very lon",
+		},
+		{
+			name:     "length cap does not split characters",
+			config:   &config.Config{MaxFilenameLength: 5},
+			title:    "Café au lait",
+			expected: "Café",
+		},
+		{
+			name:     "default length cap",
+			config:   &config.Config{},
+			title:    strings.Repeat("a", 300),
+			expected: strings.Repeat("a", config.DefaultMaxFilenameLength),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &migrator{config: test.config}
+			assert.Equal(t, test.expected, m.sanitizeName(test.title))
+		})
+	}
+}
+
+func TestSanitizePageName(t *testing.T) {
+	m := &migrator{config: &config.Config{}}
+
+	assert.Equal(t, "2024/10/Standup.md", m.sanitizePageName("2024/10/Standup"))
+	assert.Equal(t, "2024/10 10-30.md", m.sanitizePageName("2024/10 10:30.md"))
+	assert.Equal(t, "done/Report.md", m.sanitizePageName("/done//Report? "))
+	assert.Equal(t, "untitled.md", m.sanitizePageName(""))
+	assert.Equal(t, "untitled.md", m.sanitizePageName("???"))
+}
+
+func TestUniquePath(t *testing.T) {
+	m := &migrator{config: &config.Config{}, cache: NewCache()}
+
+	first := "10000000-0000-0000-0000-000000000001"
+	second := "20000000-0000-0000-0000-000000000002"
+	third := "30000000-0000-0000-0000-000000000002"
+
+	assert.Equal(t, "vault/Notes.md", m.uniquePath(first, "vault/Notes.md"))
+	assert.Equal(t, "vault/Notes (00000002).md", m.uniquePath(second, "vault/Notes.md"))
+	// Paths differing only in case are the same note on macOS and Windows.
+	assert.Equal(t, "vault/notes (30000000000000000000000000000002).md", m.uniquePath(third, "vault/notes.md"))
+	// Claiming again returns the same path.
+	assert.Equal(t, "vault/Notes.md", m.uniquePath(first, "vault/Notes.md"))
+	assert.Equal(t, "vault/Notes (00000002).md", m.uniquePath(second, "vault/Notes.md"))
+	assert.Equal(t, "vault/Other.md", m.uniquePath(second, "vault/Other.md"))
+}
+
+func TestUniquePath_MaxFilenameLength(t *testing.T) {
+	m := &migrator{config: &config.Config{MaxFilenameLength: 20}, cache: NewCache()}
+
+	first := "10000000-0000-0000-0000-000000000001"
+	second := "20000000-0000-0000-0000-000000000002"
+
+	assert.Equal(t, "vault/A very long title.md", m.uniquePath(first, "vault/A very long title.md"))
+	// The name is cut before adding the page ID, the name without `.md` keeps 20 bytes.
+	assert.Equal(t, "vault/A very lo (00000002).md", m.uniquePath(second, "vault/A very long title.md"))
+}
+
+func TestCreationOrder(t *testing.T) {
+	day := time.Date(2024, 10, 10, 0, 0, 0, 0, time.UTC)
+	pages := []notion.Page{
+		{ID: "c", CreatedTime: day.Add(time.Hour)},
+		{ID: "b", CreatedTime: day},
+		{ID: "a", CreatedTime: day},
+	}
+
+	sorted := creationOrder(pages)
+
+	assert.Equal(t, []string{"a", "b", "c"}, []string{sorted[0].ID, sorted[1].ID, sorted[2].ID})
+	assert.Equal(t, "c", pages[0].ID)
+}
@@ -0,0 +1,87 @@
+package migrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// listStarts holds the start number of the numbered lists, by the ID of their first item.
+// go-notion does not expose the list_start_index of the numbered list items, it is read from the
+// responses of the Notion API by listStartTransport.
+type listStarts struct {
+	mu     sync.Mutex
+	starts map[string]int
+}
+
+func newListStarts() *listStarts {
+	return &listStarts{starts: map[string]int{}}
+}
+
+// start returns the number of the first item of a numbered list, 1 when Notion does not set one.
+func (l *listStarts) start(blockID string) int {
+	if l == nil {
+		return 1
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if start, ok := l.starts[blockID]; ok && start > 0 {
+		return start
+	}
+
+	return 1
+}
+
+// record reads the list_start_index of the numbered list items of a list of blocks.
+func (l *listStarts) record(body []byte) {
+	var response struct {
+		Results []struct {
+			ID               string `json:"id"`
+			NumberedListItem *struct {
+				ListStartIndex int `json:"list_start_index"`
+			} `json:"numbered_list_item"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, block := range response.Results {
+		if block.NumberedListItem != nil && block.NumberedListItem.ListStartIndex > 0 {
+			l.starts[block.ID] = block.NumberedListItem.ListStartIndex
+		}
+	}
+}
+
+// listStartTransport records the start numbers of the numbered lists in the block children returned by Notion.
+type listStartTransport struct {
+	next   http.RoundTripper
+	starts *listStarts
+}
+
+func (t *listStartTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || !strings.HasSuffix(req.URL.Path, "/children") {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response body. error: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.starts.record(body)
+
+	return resp, nil
+}
@@ -1,10 +1,9 @@
 package migrator
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"fmt"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -17,314 +16,393 @@ func (m *migrator) propertiesToFrontMatter(
 	sortedKeys []string,
 	propertites notion.DatabasePageProperties,
 	buffer *strings.Builder,
-) {
-	buffer.WriteString("---\n")
-	// There is a limitation between Notions and Obsidian.
-	// If the property is named tags in Notion it has ramifications in Obsidian
-	// For example Notion relation property name tags would break in Obsidian
-	// Workaround rename the Notion property to "Related to tags"
+) error {
+	// Rollups are computed from the relations of the page, including the relations not migrated.
+	allProperties, _ := parentPage.notionPage.Properties.(notion.DatabasePageProperties)
+	relationsTruncated := false
+	for _, value := range allProperties {
+		if value.Type == notion.DBPropTypeRelation && truncated(value, false) {
+			relationsTruncated = true
+		}
+	}
+
+	// Obsidian only accepts text in the tags and aliases properties, a Notion relation named tags breaks them.
+	// The property mappings rename those properties, or turn select values into valid tags.
+	properties := newFrontmatterProperties(m.config.PropertyMappings)
 	for _, key := range sortedKeys {
 		value := propertites[key]
-		switch value.Type {
-		case notion.DBPropTypeTitle:
-			fmt.Fprintf(buffer, "%s: %s\n", key, extractPlainTextFromRichText(value.Title))
-		case notion.DBPropTypeRichText:
-			fmt.Fprintf(buffer, "%s: %s\n", key, extractPlainTextFromRichText(value.RichText))
-		case notion.DBPropTypeNumber:
-			if value.Number != nil {
-				fmt.Fprintf(buffer, "%s: %f\n", key, *value.Number)
+
+		if truncated(value, relationsTruncated) {
+			complete, err := m.completeProperty(ctx, parentPage.notionPage.ID, value)
+			if err != nil {
+				// The values included in the page are kept
+				m.logger.Info(fmt.Sprintf("%s\n", err))
 			} else {
-				fmt.Fprintf(buffer, "%s: \n", key)
-			}
-		case notion.DBPropTypeSelect:
-			if value.Select != nil {
-				fmt.Fprintf(buffer, "%s: %s\n", key, value.Select.Name)
-			}
-		case notion.DBPropTypeMultiSelect:
-			options := []string{}
-			for _, option := range value.MultiSelect {
-				options = append(options, option.Name)
+				value = complete
 			}
+		}
 
-			fmt.Fprintf(buffer, "%s: [%s]\n", key, strings.Join(options, ","))
-		case notion.DBPropTypeDate:
-			if value.Date != nil {
-				if value.Date.Start.HasTime() {
-					fmt.Fprintf(buffer, "%s: %s\n", key, value.Date.Start.Format("2006-01-02T15:04:05"))
-				} else {
-					fmt.Fprintf(buffer, "%s: %s\n", key, value.Date.Start.Format("2006-01-02"))
-				}
+		if date, ok := propertyDate(value); ok {
+			m.addDate(properties, key, date)
+			continue
+		}
+
+		property, ok := m.propertyValue(ctx, parentPage, value)
+		if !ok {
+			continue
+		}
+
+		properties.add(key, property)
+	}
+
+	result, err := properties.frontmatter()
+	if err != nil {
+		return err
+	}
+
+	buffer.WriteString(result.String())
+
+	return nil
+}
+
+// propertyValue returns the frontmatter value of a page property, or false when the property is skipped.
+func (m *migrator) propertyValue(ctx context.Context, parentPage *Page, value notion.DatabasePageProperty) (any, bool) {
+	var property any
+	switch value.Type {
+	case notion.DBPropTypeTitle:
+		property = extractPlainTextFromRichText(value.Title)
+	case notion.DBPropTypeRichText:
+		property = extractPlainTextFromRichText(value.RichText)
+	case notion.DBPropTypeNumber:
+		if value.Number != nil {
+			property = *value.Number
+		}
+	case notion.DBPropTypeSelect:
+		if value.Select == nil {
+			return nil, false
+		}
+		property = value.Select.Name
+	case notion.DBPropTypeMultiSelect:
+		options := []string{}
+		for _, option := range value.MultiSelect {
+			options = append(options, option.Name)
+		}
+		property = options
+	case notion.DBPropTypeDate:
+		if value.Date == nil {
+			return nil, false
+		}
+		property = m.dateRange(*value.Date)
+	case notion.DBPropTypePeople:
+		people := []string{}
+		for _, user := range value.People {
+			people = append(people, m.personName(ctx, user))
+		}
+		property = people
+	case notion.DBPropTypeFiles:
+		files := []string{}
+		for _, file := range value.Files {
+			files = append(files, m.fileProperty(parentPage, file))
+		}
+		property = files
+	case notion.DBPropTypeCheckbox:
+		if value.Checkbox != nil {
+			property = *value.Checkbox
+		}
+	case notion.DBPropTypeURL:
+		if value.URL != nil {
+			property = *value.URL
+		}
+	case notion.DBPropTypeEmail:
+		if value.Email != nil {
+			property = *value.Email
+		}
+	case notion.DBPropTypePhoneNumber:
+		if value.PhoneNumber != nil {
+			property = *value.PhoneNumber
+		}
+	case notion.DBPropTypeStatus:
+		if value.Status == nil {
+			return nil, false
+		}
+		property = value.Status.Name
+	case notion.DBPropTypeFormula:
+		if value.Formula == nil {
+			return nil, false
+		}
+		switch value.Formula.Type {
+		case notion.FormulaResultTypeString:
+			if value.Formula.String != nil {
+				property = *value.Formula.String
 			}
-		case notion.DBPropTypePeople:
-		case notion.DBPropTypeFiles:
-		case notion.DBPropTypeCheckbox:
-			if value.Checkbox != nil {
-				fmt.Fprintf(buffer, "%s: %t\n", key, *value.Checkbox)
-			} else {
-				fmt.Fprintf(buffer, "%s: \n", key)
+		case notion.FormulaResultTypeNumber:
+			if value.Formula.Number != nil {
+				property = *value.Formula.Number
 			}
-		case notion.DBPropTypeURL:
-			if value.URL != nil {
-				fmt.Fprintf(buffer, "%s: %s\n", key, *value.URL)
-			} else {
-				fmt.Fprintf(buffer, "%s: \n", key)
+		case notion.FormulaResultTypeBoolean:
+			if value.Formula.Boolean != nil {
+				property = *value.Formula.Boolean
 			}
-		case notion.DBPropTypeEmail:
-			if value.Email != nil {
-				fmt.Fprintf(buffer, "%s: %s\n", key, *value.Email)
-			} else {
-				fmt.Fprintf(buffer, "%s: \n", key)
+		case notion.FormulaResultTypeDate:
+			if value.Formula.Date == nil {
+				return nil, false
 			}
-		case notion.DBPropTypePhoneNumber:
-			if value.PhoneNumber != nil {
-				fmt.Fprintf(buffer, "%s: %s\n", key, *value.PhoneNumber)
-			} else {
-				fmt.Fprintf(buffer, "%s: \n", key)
-			}
-		case notion.DBPropTypeStatus:
-			fmt.Fprintf(buffer, "%s: %s\n", key, value.Status.Name)
-		case notion.DBPropTypeFormula:
-		case notion.DBPropTypeRelation:
-			// TODO: Needs to handle relations bigger then 25
-			// https://developers.notion.com/reference/retrieve-a-page-property
+			property = m.dateRange(*value.Formula.Date)
+		}
+	case notion.DBPropTypeRelation:
+		links := []string{}
+		for _, relation := range value.Relation {
 			b := &strings.Builder{}
-			for i, relation := range value.Relation {
-				if i == 0 {
-					b.WriteString("\n  - ")
-				} else {
-					b.WriteString("  - ")
-				}
-
-				err := m.fetchPage(ctx, parentPage, relation.ID, "", b, true)
-				if err != nil {
-					// We do not want to break the migration proccess for this case
-					m.logger.Info("failed to get page relation for frontmatter")
+			err := m.fetchPage(ctx, parentPage, relation.ID, "", b, false)
+			if err != nil || b.Len() == 0 {
+				// We do not want to break the migration proccess for this case
+				m.logger.Info("failed to get page relation for frontmatter")
+				continue
+			}
+			links = append(links, b.String())
+		}
+		property = links
+	case notion.DBPropTypeRollup:
+		if value.Rollup == nil {
+			return nil, false
+		}
+		switch value.Rollup.Type {
+		case notion.RollupResultTypeNumber:
+			if value.Rollup.Number != nil {
+				property = *value.Rollup.Number
+			}
+		case notion.RollupResultTypeDate:
+			if value.Rollup.Date == nil {
+				return nil, false
+			}
+			property = m.dateRange(*value.Rollup.Date)
+		case notion.RollupResultTypeArray:
+			// The values of the related pages are merged in a single list,
+			// relations and multi-selects of the related pages add every value.
+			values := []any{}
+			for _, element := range value.Rollup.Array {
+				item, ok := m.propertyValue(ctx, parentPage, element)
+				if !ok {
 					continue
 				}
-				b.WriteString("\n")
-			}
-			fmt.Fprintf(buffer, "%s: %s\n", key, b.String())
-		case notion.DBPropTypeRollup:
-			switch value.Rollup.Type {
-			case notion.RollupResultTypeNumber:
-				if value.Rollup.Number != nil {
-					fmt.Fprintf(buffer, "%s: %f\n", key, *value.Rollup.Number)
-				} else {
-					fmt.Fprintf(buffer, "%s: \n", key)
-				}
-			case notion.RollupResultTypeDate:
-				if value.Rollup.Date.Start.HasTime() {
-					fmt.Fprintf(buffer, "%s: %s\n", key, value.Rollup.Date.Start.Format("2006-01-02T15:04:05"))
-				} else {
-					fmt.Fprintf(buffer, "%s: %s\n", key, value.Rollup.Date.Start.Format("2006-01-02"))
-				}
-			case notion.RollupResultTypeArray:
-				b := &bytes.Buffer{}
-				rollupBuffer := bufio.NewWriter(b)
-				if _, err := fmt.Fprintf(rollupBuffer, "%s: ", key); err != nil {
-					m.logger.Error(fmt.Sprintf("failed to write rollup key: %v", err))
-				}
+				values = append(values, listValues(item)...)
+			}
+			property = values
+		case notion.RollupResultTypeUnsupported:
+			// Unsupported rollup results are skipped
+			return nil, false
+		case notion.RollupResultTypeIncomplete:
+			// Incomplete rollup results are skipped
+			return nil, false
+		}
+	case notion.DBPropTypeCreatedTime, notion.DBPropTypeLastEditedTime:
+		date, ok := propertyDate(value)
+		if !ok {
+			return nil, false
+		}
+		property = m.dateRange(date)
+	case notion.DBPropTypeCreatedBy:
+		if value.CreatedBy != nil {
+			property = value.CreatedBy.Name
+		}
+	case notion.DBPropTypeLastEditedBy:
+		if value.LastEditedBy != nil {
+			property = value.LastEditedBy.Name
+		}
+	case notion.DBPropTypePropertyItem:
+		// PropertyItem type is not supported for frontmatter
+		return nil, false
+	default:
+		return nil, false
+	}
 
-				numbers := []float64{}
-				for _, prop := range value.Rollup.Array {
-					if prop.Type == notion.DBPropTypeNumber {
-						numbers = append(numbers, *prop.Number)
-					}
-				}
+	return property, true
+}
 
-				if _, err := fmt.Fprintf(rollupBuffer, "%f\n", numbers); err != nil {
-					m.logger.Error(fmt.Sprintf("failed to write rollup numbers: %v", err))
-				}
-				if err := rollupBuffer.Flush(); err != nil {
-					m.logger.Error(fmt.Sprintf("failed to flush rollup buffer: %v", err))
-				}
-				buffer.WriteString(b.String())
-			case notion.RollupResultTypeUnsupported:
-				// Unsupported rollup results are skipped
-			case notion.RollupResultTypeIncomplete:
-				// Incomplete rollup results are skipped
-			}
-		case notion.DBPropTypeCreatedTime:
-			fmt.Fprintf(buffer, "%s: %s\n", key, value.CreatedTime.String())
-		case notion.DBPropTypeCreatedBy:
-			fmt.Fprintf(buffer, "%s: %s\n", key, value.CreatedBy.Name)
-		case notion.DBPropTypeLastEditedTime:
-			fmt.Fprintf(buffer, "%s: %s\n", key, value.LastEditedTime.String())
-		case notion.DBPropTypeLastEditedBy:
-			fmt.Fprintf(buffer, "%s: %s\n", key, value.LastEditedBy.Name)
-		case notion.DBPropTypePropertyItem:
-			// PropertyItem type is not supported for frontmatter
-		default:
+// personName returns the name of a user of a people property.
+// Pages only include the user ID when the integration can not read user information,
+// the name is then fetched from the users endpoint, falling back to the email or ID.
+func (m *migrator) personName(ctx context.Context, user notion.User) string {
+	if user.Name != "" {
+		return user.Name
+	}
+
+	m.usersMu.Lock()
+	defer m.usersMu.Unlock()
+
+	if name, ok := m.users[user.ID]; ok {
+		return name
+	}
+
+	name := user.ID
+	if user.Person != nil && user.Person.Email != "" {
+		name = user.Person.Email
+	}
+
+	notionUser, err := m.notionClient.FindUserByID(ctx, user.ID)
+	if err != nil {
+		// We do not want to break the migration proccess for this case
+		m.logger.Info(fmt.Sprintf("failed to get user %s for frontmatter. error: %s\n", user.ID, err))
+	} else if notionUser.Name != "" {
+		name = notionUser.Name
+	} else if notionUser.Person != nil && notionUser.Person.Email != "" {
+		name = notionUser.Person.Email
+	}
+
+	m.users[user.ID] = name
+
+	return name
+}
+
+// fileProperty returns the value of a file of a files property.
+// Files uploaded to Notion are downloaded to the vault with the images when storing images,
+// the URL Notion returns for them expires after an hour.
+func (m *migrator) fileProperty(parentPage *Page, file notion.File) string {
+	switch {
+	case file.Type == notion.FileTypeExternal && file.External != nil:
+		return file.External.URL
+	case file.Type == notion.FileTypeFile && file.File != nil:
+		if !m.config.StoreImages {
+			return file.File.URL
 		}
+
+		name := filepath.Base(file.Name)
+		if file.Name == "" {
+			name = path.Base(strings.Split(file.File.URL, "?")[0])
+		}
+		fileName := filepath.Join(parentPage.title, name)
+
+		parentPage.images = append(parentPage.images, &image{
+			external: false,
+			url:      file.File.URL,
+			name:     fileName,
+		})
+
+		return fmt.Sprintf("[[%s]]", filepath.Join("Images", fileName))
+	default:
+		return file.Name
 	}
-	buffer.WriteString("---\n")
 }
 
-func (m *migrator) pageToMarkdown(ctx context.Context, parentPage *Page, blocks []notion.Block, indent bool) error {
+// The prefixes of the children of the blocks: list items indent their children, quotes prefix them with `>`.
+const (
+	listIndent  = "\t"
+	quotePrefix = "> "
+)
+
+// pageToMarkdown writes the blocks in the page buffer.
+// The containers indent or prefix the lines of their children at any nesting level, see writeChrildren.
+func (m *migrator) pageToMarkdown(ctx context.Context, parentPage *Page, blocks []notion.Block) error {
 	var err error
 	buffer := parentPage.buffer
+	// number is the number of the numbered list item in its list. Like in Notion, any other block
+	// ends the list, and the children of an item start their own list.
+	number := 0
 
 	for _, object := range blocks {
+		if _, ok := object.(*notion.NumberedListItemBlock); !ok {
+			number = 0
+		}
+
 		switch block := object.(type) {
 		case *notion.Heading1Block:
-			if indent {
-				buffer.WriteString("	# ")
-			} else {
-				buffer.WriteString("# ")
-			}
+			buffer.WriteString("# ")
 			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
 				return err
 			}
 			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, m.headingChildrenPrefix(block.IsToggleable)); err != nil {
 				return err
 			}
 		case *notion.Heading2Block:
-			if indent {
-				buffer.WriteString("	## ")
-			} else {
-				buffer.WriteString("## ")
-			}
+			buffer.WriteString("## ")
 			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
 				return err
 			}
 			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, m.headingChildrenPrefix(block.IsToggleable)); err != nil {
 				return err
 			}
 		case *notion.Heading3Block:
-			if indent {
-				buffer.WriteString("	### ")
-			} else {
-				buffer.WriteString("### ")
-			}
+			buffer.WriteString("### ")
 			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
 				return err
 			}
 			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, m.headingChildrenPrefix(block.IsToggleable)); err != nil {
 				return err
 			}
 		case *notion.ToDoBlock:
-			if indent {
-				if *block.Checked {
-					buffer.WriteString("	- [x] ")
-				} else {
-					buffer.WriteString("	- [ ] ")
-				}
+			if *block.Checked {
+				buffer.WriteString("- [x] ")
 			} else {
-				if *block.Checked {
-					buffer.WriteString("- [x] ")
-				} else {
-					buffer.WriteString("- [ ] ")
-				}
+				buffer.WriteString("- [ ] ")
 			}
 			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
 				return err
 			}
 			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, listIndent); err != nil {
 				return err
 			}
 		case *notion.ParagraphBlock:
 			if len(block.RichText) > 0 {
-				if indent {
-					buffer.WriteString("	")
-					if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
-						return err
-					}
-				} else {
-					if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
-						return err
-					}
+				if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
+					return err
 				}
 			}
 			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, listIndent); err != nil {
 				return err
 			}
 		case *notion.BulletedListItemBlock:
-			if indent {
-				buffer.WriteString("	- ")
-			} else {
-				buffer.WriteString("- ")
-			}
+			buffer.WriteString("- ")
 			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
 				return err
 			}
 			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, listIndent); err != nil {
 				return err
 			}
 		case *notion.NumberedListItemBlock:
-			if indent {
-				buffer.WriteString("	- ")
-			} else {
-				buffer.WriteString("- ")
+			// The first item holds the start number of the list.
+			if number == 0 {
+				number = m.listStarts.start(block.ID()) - 1
 			}
+			number++
+			fmt.Fprintf(buffer, "%d. ", number)
 			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
 				return err
 			}
 			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, listIndent); err != nil {
 				return err
 			}
 		case *notion.CalloutBlock:
-			if indent {
-				buffer.WriteString("	> [!")
-			} else {
-				buffer.WriteString("> [!")
-			}
-			if len(*block.Icon.Emoji) > 0 {
-				buffer.WriteString(*block.Icon.Emoji)
-			}
-			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
+			if err = m.writeCallout(ctx, parentPage, block); err != nil {
 				return err
 			}
-			buffer.WriteString("]")
-			buffer.WriteString("\n")
 		case *notion.ToggleBlock:
-			if indent {
-				buffer.WriteString("	- ")
-			} else {
-				buffer.WriteString("- ")
-			}
-			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
-				return err
-			}
-			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeToggle(ctx, parentPage, block); err != nil {
 				return err
 			}
 		case *notion.QuoteBlock:
-			if indent {
-				buffer.WriteString("	> ")
-			} else {
-				buffer.WriteString("> ")
-			}
+			buffer.WriteString("> ")
 			if err = m.writeRichText(ctx, parentPage, block.RichText); err != nil {
 				return err
 			}
 			buffer.WriteString("\n")
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, quotePrefix); err != nil {
 				return err
 			}
 		case *notion.FileBlock:
 			if block.Type == notion.FileTypeExternal {
-				if indent {
-					fmt.Fprintf(buffer, "	![](%s)", block.External.URL)
-				} else {
-					fmt.Fprintf(buffer, "![](%s)", block.External.URL)
-				}
+				fmt.Fprintf(buffer, "![](%s)", block.External.URL)
 			}
 			buffer.WriteString("\n")
 		case *notion.PDFBlock:
 			if block.Type == notion.FileTypeExternal {
-				if indent {
-					fmt.Fprintf(buffer, "	![](%s)", block.External.URL)
-				} else {
-					fmt.Fprintf(buffer, "![](%s)", block.External.URL)
-				}
+				fmt.Fprintf(buffer, "![](%s)", block.External.URL)
 				buffer.WriteString("\n")
 			} else if m.config.StoreImages {
 				imageName := filepath.Join(parentPage.title, block.ID()+".pdf")
@@ -335,19 +413,17 @@ func (m *migrator) pageToMarkdown(ctx context.Context, parentPage *Page, blocks
 					name:     imageName,
 				})
 
-				if indent {
-					fmt.Fprintf(buffer, "	![[%s]]", filepath.Join("Images", imageName))
-				} else {
-					fmt.Fprintf(buffer, "![[%s]]", filepath.Join("Images", imageName))
-				}
+				fmt.Fprintf(buffer, "![[%s]]", filepath.Join("Images", imageName))
 				buffer.WriteString("\n")
 			}
 		case *notion.DividerBlock:
 			buffer.WriteString("---")
 			buffer.WriteString("\n")
 		case *notion.ChildPageBlock:
-			if indent {
-				fmt.Fprintf(buffer, " [[%s]]", block.Title)
+			if m.migrateChildren(parentPage) {
+				if err = m.fetchChildPage(ctx, parentPage, block.ID(), buffer); err != nil {
+					return err
+				}
 			} else {
 				fmt.Fprintf(buffer, "[[%s]]", block.Title)
 			}
@@ -359,11 +435,7 @@ func (m *migrator) pageToMarkdown(ctx context.Context, parentPage *Page, blocks
 			}
 			buffer.WriteString("\n")
 		case *notion.LinkPreviewBlock:
-			if indent {
-				fmt.Fprintf(buffer, "	![](%s)", block.URL)
-			} else {
-				fmt.Fprintf(buffer, "![](%s)", block.URL)
-			}
+			fmt.Fprintf(buffer, "![](%s)", block.URL)
 			buffer.WriteString("\n")
 		case *notion.CodeBlock:
 			buffer.WriteString("```")
@@ -377,11 +449,7 @@ func (m *migrator) pageToMarkdown(ctx context.Context, parentPage *Page, blocks
 			buffer.WriteString("\n")
 		case *notion.ImageBlock:
 			if block.Type == notion.FileTypeExternal {
-				if indent {
-					fmt.Fprintf(buffer, "	![](%s)", block.External.URL)
-				} else {
-					fmt.Fprintf(buffer, "![](%s)", block.External.URL)
-				}
+				fmt.Fprintf(buffer, "![](%s)", block.External.URL)
 				buffer.WriteString("\n")
 			}
 			if block.Type == notion.FileTypeFile && m.config.StoreImages {
@@ -393,51 +461,39 @@ func (m *migrator) pageToMarkdown(ctx context.Context, parentPage *Page, blocks
 					name:     imageName,
 				})
 
-				if indent {
-					fmt.Fprintf(buffer, "	![[%s]]", filepath.Join("Images", imageName))
-				} else {
-					fmt.Fprintf(buffer, "![[%s]]", filepath.Join("Images", imageName))
-				}
+				fmt.Fprintf(buffer, "![[%s]]", filepath.Join("Images", imageName))
 				buffer.WriteString("\n")
 			}
 		case *notion.VideoBlock:
 			if block.Type == notion.FileTypeExternal {
-				if indent {
-					fmt.Fprintf(buffer, "	![](%s)", block.External.URL)
-				} else {
-					fmt.Fprintf(buffer, "![](%s)", block.External.URL)
-				}
+				fmt.Fprintf(buffer, "![](%s)", block.External.URL)
 			}
 			buffer.WriteString("\n")
 		case *notion.EmbedBlock:
-			if indent {
-				fmt.Fprintf(buffer, "	![](%s)", block.URL)
-			} else {
-				fmt.Fprintf(buffer, "![](%s)", block.URL)
-			}
+			fmt.Fprintf(buffer, "![](%s)", block.URL)
 			buffer.WriteString("\n")
 		case *notion.BookmarkBlock:
-			if indent {
-				fmt.Fprintf(buffer, "	![](%s)", block.URL)
-			} else {
-				fmt.Fprintf(buffer, "![](%s)", block.URL)
-			}
+			fmt.Fprintf(buffer, "![](%s)", block.URL)
 			buffer.WriteString("\n")
 		case *notion.ChildDatabaseBlock:
-			m.logger.Warn(fmt.Sprintf("Child database `%s` found on page `%s`. You might want to migrate that database separately", block.Title, m.removeObsidianVault(parentPage.Path)))
-
-			if indent {
-				fmt.Fprintf(buffer, "	%s", block.Title)
-			} else {
-				buffer.WriteString(block.Title)
+			if m.migrateChildren(parentPage) {
+				if err = m.fetchChildDatabase(ctx, parentPage, block.ID(), buffer); err != nil {
+					return err
+				}
+				continue
 			}
+
+			m.logger.Warn(fmt.Sprintf("Child database `%s` found on page `%s`. You might want to migrate that database separately or use -recursive", block.Title, m.removeObsidianVault(parentPage.Path)))
+
+			buffer.WriteString(block.Title)
 			buffer.WriteString("\n")
 		case *notion.ColumnListBlock:
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			// Columns are only a layout, their content is written at the same level.
+			if err = m.writeChrildren(ctx, parentPage, object, ""); err != nil {
 				return err
 			}
 		case *notion.ColumnBlock:
-			if err = m.writeChrildren(ctx, parentPage, object); err != nil {
+			if err = m.writeChrildren(ctx, parentPage, object, ""); err != nil {
 				return err
 			}
 		case *notion.TableBlock:
@@ -445,14 +501,14 @@ func (m *migrator) pageToMarkdown(ctx context.Context, parentPage *Page, blocks
 				return err
 			}
 		case *notion.EquationBlock:
-			if indent {
-				fmt.Fprintf(buffer, " $$%s$$", block.Expression)
-			} else {
-				fmt.Fprintf(buffer, "$$%s$$", block.Expression)
-			}
+			fmt.Fprintf(buffer, "$$%s$$", block.Expression)
 			buffer.WriteString("\n")
 		case *notion.TableOfContentsBlock:
 		case *notion.BreadcrumbBlock:
+		case *notion.SyncedBlock:
+			if err = m.writeSyncedBlock(ctx, parentPage, block); err != nil {
+				return err
+			}
 		case *notion.UnsupportedBlock:
 		default:
 			return fmt.Errorf("block not supported: %+v", block)
@@ -522,8 +578,7 @@ func (m *migrator) writeRichText(ctx context.Context, parentPage *Page, richText
 				value := "[[" + text.PlainText + "]]"
 				richTextBuffer.WriteString(value)
 			case notion.MentionTypeDate:
-				value := "[[" + text.Mention.Date.Start.Format("2006-01-02") + "]]"
-				richTextBuffer.WriteString(value)
+				richTextBuffer.WriteString(m.dateMention(*text.Mention.Date))
 			case notion.MentionTypeLinkPreview:
 				richTextBuffer.WriteString(text.Mention.LinkPreview.URL)
 			case notion.MentionTypeTemplateMention:
@@ -574,18 +629,68 @@ func (m *migrator) writeRichText(ctx context.Context, parentPage *Page, richText
 	return nil
 }
 
-func (m *migrator) writeChrildren(ctx context.Context, parentPage *Page, block notion.Block) error {
-	if block.HasChildren() {
-		pageBlocks, err := m.notionClient.FindBlockChildrenByID(ctx, block.ID(), nil)
-		if err != nil {
-			return fmt.Errorf("failed to extract children blocks for block ID %s. error: %w", block.ID(), err)
-		}
-		return m.pageToMarkdown(ctx, parentPage, pageBlocks.Results, true)
+// writeChrildren writes the children of block, starting every line with prefix.
+// The prefixes add up, a list inside a quote inside a list item is written as `\t> \t- `.
+func (m *migrator) writeChrildren(ctx context.Context, parentPage *Page, block notion.Block, prefix string) error {
+	if !block.HasChildren() {
+		return nil
+	}
+
+	pageBlocks, err := m.fetchBlockChildren(ctx, block.ID())
+	if err != nil {
+		return fmt.Errorf("failed to extract children blocks for block ID %s. error: %w", block.ID(), err)
 	}
 
+	// The children are written in their own buffer, so every line they write gets the prefix,
+	// including the lines of multi-line text and code blocks.
+	children, err := m.capture(parentPage, func() error {
+		return m.pageToMarkdown(ctx, parentPage, pageBlocks)
+	})
+	if err != nil {
+		return err
+	}
+
+	parentPage.buffer.WriteString(prefixLines(children, prefix))
+
 	return nil
 }
 
+// capture returns what write writes in the page buffer, instead of adding it to the page.
+func (m *migrator) capture(parentPage *Page, write func() error) (string, error) {
+	buffer := parentPage.buffer
+	parentPage.buffer = &strings.Builder{}
+	defer func() { parentPage.buffer = buffer }()
+
+	err := write()
+
+	return parentPage.buffer.String(), err
+}
+
+// prefixLines starts every line of text with prefix. Empty lines get the prefix without trailing spaces,
+// so they do not end a quote nor add whitespace to the note.
+func prefixLines(text, prefix string) string {
+	if prefix == "" || text == "" {
+		return text
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	result := &strings.Builder{}
+
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		if line == "\n" {
+			result.WriteString(strings.TrimRight(prefix, " \t"))
+		} else {
+			result.WriteString(prefix)
+		}
+		result.WriteString(line)
+	}
+
+	return result.String()
+}
+
 func (m *migrator) writeTable(
 	ctx context.Context,
 	parentPage *Page,
@@ -594,12 +699,12 @@ func (m *migrator) writeTable(
 	buffer *strings.Builder,
 ) error {
 	if block.HasChildren() {
-		pageBlocks, err := m.notionClient.FindBlockChildrenByID(ctx, block.ID(), nil)
+		pageBlocks, err := m.fetchBlockChildren(ctx, block.ID())
 		if err != nil {
 			return fmt.Errorf("failed to extract table children blocks for block ID %s. error: %w", block.ID(), err)
 		}
 
-		for rowIndex, object := range pageBlocks.Results {
+		for rowIndex, object := range pageBlocks {
 			row, ok := object.(*notion.TableRowBlock)
 			if !ok {
 				return fmt.Errorf("expected TableRowBlock, got %T", object)
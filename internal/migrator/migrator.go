@@ -11,11 +11,17 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"text/template"
 
+	"github.com/GustavoCaso/n2o/internal/apicache"
 	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/GustavoCaso/n2o/internal/dbquery"
+	"github.com/GustavoCaso/n2o/internal/httpclient"
 	"github.com/GustavoCaso/n2o/internal/log"
+	"github.com/GustavoCaso/n2o/internal/ratelimit"
+	"github.com/GustavoCaso/n2o/internal/state"
 	"github.com/dstotijn/go-notion"
-	"github.com/itchyny/timefmt-go"
 )
 
 type image struct {
@@ -24,6 +30,21 @@ type image struct {
 	name     string
 }
 
+// coverImage returns the cover of a page, nil when the page has none.
+// The URL of a cover uploaded to Notion expires after an hour, like the other files uploaded to Notion.
+func coverImage(cover *notion.Cover) *image {
+	switch {
+	case cover == nil:
+		return nil
+	case cover.Type == notion.FileTypeExternal && cover.External != nil:
+		return &image{external: true, url: cover.External.URL}
+	case cover.Type == notion.FileTypeFile && cover.File != nil:
+		return &image{external: false, url: cover.File.URL}
+	default:
+		return nil
+	}
+}
+
 type Page struct {
 	Path       string
 	title      string
@@ -34,12 +55,33 @@ type Page struct {
 	images     []*image
 	parent     *Page
 	children   []*Page
+	// depth is the number of child page levels between the page and the migrated page or database.
+	depth int
+	// unchanged marks the pages not edited since the last sync, their content is not fetched nor written.
+	unchanged bool
+	// syncedCopies are the duplicates of synced blocks in the page, resolved once every page is fetched.
+	syncedCopies []syncedCopy
+}
+
+// ID returns the Notion ID of the page.
+func (p *Page) ID() string {
+	return p.id
 }
 
 func (p *Page) String() string {
+	return p.describe(map[*Page]bool{})
+}
+
+// describe lists the child pages once, pages mentioning each other are children of each other.
+func (p *Page) describe(visited map[*Page]bool) string {
+	if visited[p] {
+		return p.Path
+	}
+	visited[p] = true
+
 	childPages := make([]string, len(p.children))
 	for i, page := range p.children {
-		childPages[i] = page.String()
+		childPages[i] = page.describe(visited)
 	}
 
 	return fmt.Sprintf("%s child pages: %s", p.Path, childPages)
@@ -59,28 +101,227 @@ type migrator struct {
 	pages        []*Page
 	logger       log.Log
 	httpClient   *http.Client
+	state        *state.State
+	// listed holds every page of the source, including the pages skipped because they did not change.
+	listed []*Page
+	// users caches the names of the people fetched from the users endpoint, by user ID.
+	users   map[string]string
+	usersMu sync.Mutex
+	// pageNameTemplate builds the page names when the configuration has a page name template.
+	pageNameTemplate *template.Template
+	// filter and sorts select and order the pages of the migrated database.
+	filter *dbquery.Filter
+	sorts  []notion.DatabaseQuerySort
+	// filenameReplacer replaces the characters not allowed in note names, it is built on first use.
+	filenameReplacer     *strings.Replacer
+	filenameReplacerOnce sync.Once
+	// failed holds the IDs of the pages that could not be migrated, they are not written nor recorded in the state.
+	failed   map[string]bool
+	failedMu sync.Mutex
+	// listStarts holds the start numbers of the numbered lists.
+	listStarts *listStarts
+	// childDatabases holds the child databases by ID, they are fetched once whatever the blocks showing them.
+	childDatabases   map[string]*childDatabase
+	childDatabasesMu sync.Mutex
+}
+
+type Option func(*migratorOptions)
+
+type migratorOptions struct {
+	notionHTTPClient *http.Client
+	httpClient       *http.Client
+	state            *state.State
+}
+
+// WithNotionHTTPClient sets the HTTP client used for the Notion API.
+// Migrators sharing the client share the Notion API rate limit.
+func WithNotionHTTPClient(client *http.Client) Option {
+	return func(o *migratorOptions) {
+		o.notionHTTPClient = client
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to download the images.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *migratorOptions) {
+		o.httpClient = client
+	}
+}
+
+// WithState records the migrated pages in the state of the Obsidian vault.
+// The state is required to sync the pages edited since the last run.
+func WithState(s *state.State) Option {
+	return func(o *migratorOptions) {
+		o.state = s
+	}
+}
+
+// NewNotionHTTPClient returns an HTTP client throttling the requests to the Notion API
+// and retrying the rate limited and failed requests.
+// When the cache is enabled, the responses are cached on disk and cached responses skip the rate limit.
+func NewNotionHTTPClient(config *config.Config) (*http.Client, error) {
+	transport, err := newHTTPTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.NotionBaseURL != "" {
+		transport, err = httpclient.NewBaseURLTransport(transport, config.NotionBaseURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	options := ratelimit.DefaultOptions()
+	options.RequestsPerSecond = config.RequestsPerSecond
+	options.Burst = config.RateLimitBurst
+	options.MaxRetries = config.MaxRetries
+
+	transport = ratelimit.NewTransport(transport, options)
+
+	if config.Cache || config.Offline {
+		transport = apicache.NewTransport(transport, apicache.Options{
+			Dir:     CacheDir(config),
+			TTL:     config.CacheTTL,
+			Offline: config.Offline,
+		})
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}, nil
+}
+
+// NewHTTPClient returns the HTTP client downloading the images,
+// going through the same proxy, CA bundle, timeout and User-Agent as the requests to Notion.
+func NewHTTPClient(config *config.Config) (*http.Client, error) {
+	transport, err := newHTTPTransport(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Transport: transport,
+	}, nil
 }
 
-func NewMigrator(config *config.Config, cache *Cache, logger log.Log) Migrator {
-	notionClient := notion.NewClient(config.Token)
+func newHTTPTransport(config *config.Config) (http.RoundTripper, error) {
+	return httpclient.NewTransport(httpclient.Options{
+		Proxy:     config.Proxy,
+		CABundle:  config.CABundle,
+		Timeout:   config.RequestTimeout,
+		UserAgent: config.UserAgent,
+	})
+}
+
+// CacheDir returns the folder storing the Notion API responses. By default it is stored inside the Obsidian vault.
+func CacheDir(config *config.Config) string {
+	if config.CacheDir != "" {
+		return config.CacheDir
+	}
+
+	return filepath.Join(config.VaultPath, state.Dir, "cache")
+}
+
+func NewMigrator(config *config.Config, cache *Cache, logger log.Log, opts ...Option) (Migrator, error) {
+	options := &migratorOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	var err error
+
+	if options.notionHTTPClient == nil {
+		options.notionHTTPClient, err = NewNotionHTTPClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if options.httpClient == nil {
+		options.httpClient, err = NewHTTPClient(config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The client is copied to read the start numbers of the numbered lists, the copy keeps the shared transport.
+	starts := newListStarts()
+	notionHTTPClient := *options.notionHTTPClient
+	transport := notionHTTPClient.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	notionHTTPClient.Transport = &listStartTransport{next: transport, starts: starts}
+
+	notionClient := notion.NewClient(config.Token, notion.WithHTTPClient(&notionHTTPClient))
 
-	return &migrator{
+	m := &migrator{
 		notionClient: notionClient,
 		config:       config,
 		cache:        cache,
 		logger:       logger,
-		httpClient:   http.DefaultClient,
+		httpClient:   options.httpClient,
+		state:        options.state,
+		users:        map[string]string{},
+		listStarts:   starts,
 	}
+
+	m.pageNameTemplate, err = m.parsePageNameTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	m.filter, err = dbquery.ParseFilter(config.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	m.sorts, err = dbquery.ParseSorts(config.Sorts)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
 }
 
+// FetchPages returns the pages to migrate. When syncing, the pages not edited since the last run are skipped.
 func (m *migrator) FetchPages(ctx context.Context) ([]*Page, error) {
+	pages, err := m.fetchSourcePages(ctx)
+	if err != nil {
+		return pages, err
+	}
+
+	m.listed = pages
+
+	if m.syncing() {
+		pages = m.changedPages(ctx, pages)
+	}
+
+	m.pages = pages
+
+	return pages, nil
+}
+
+func (m *migrator) fetchSourcePages(ctx context.Context) ([]*Page, error) {
+	if m.config.Workspace {
+		return m.fetchWorkspacePages(ctx)
+	}
+
 	if m.config.DatabaseID != "" {
 		db, err := m.notionClient.FindDatabaseByID(ctx, m.config.DatabaseID)
 		if err != nil {
 			return []*Page{}, fmt.Errorf("failed to get DB %s. error: %s", m.config.DatabaseID, err.Error())
 		}
-		dbTitle := extractPlainTextFromRichText(db.Title)
-		notionPages, err := m.fetchNotionDBPages(ctx)
+		if err = m.validatePageNameTemplate(db.Properties); err != nil {
+			return []*Page{}, err
+		}
+		query, err := dbquery.Build(m.filter, m.sorts, db.Properties)
+		if err != nil {
+			return []*Page{}, fmt.Errorf("failed to query DB %s. error: %w", m.config.DatabaseID, err)
+		}
+		dbTitle := m.sanitizeName(extractPlainTextFromRichText(db.Title))
+		notionPages, err := m.fetchNotionDBPages(ctx, m.config.DatabaseID, query)
 		if err != nil {
 			return []*Page{}, fmt.Errorf(
 				"failed to get pages from DB %s. error: %s",
@@ -88,33 +329,32 @@ func (m *migrator) FetchPages(ctx context.Context) ([]*Page, error) {
 				err.Error(),
 			)
 		}
+		folder := path.Join(m.config.VaultFilepath(), dbTitle)
+
+		// Pages with the same name are told apart by their ID, the oldest page keeps the name.
+		paths := map[string]string{}
+		for _, notionPage := range creationOrder(notionPages) {
+			paths[notionPage.ID] = m.uniquePath(notionPage.ID, path.Join(folder, m.extractPageTitle(notionPage)))
+		}
+
 		pages := make([]*Page, len(notionPages))
 
 		for i, notionPage := range notionPages {
-			title := m.extractPageTitle(notionPage)
 			page := &Page{
 				id:         notionPage.ID,
 				buffer:     &strings.Builder{},
-				title:      title,
-				Path:       path.Join(m.config.VaultFilepath(), dbTitle, title),
+				title:      strings.TrimPrefix(paths[notionPage.ID], folder+"/"),
+				Path:       paths[notionPage.ID],
 				notionPage: notionPage,
 				parent:     nil,
 			}
 
-			if notionPage.Cover != nil {
-				cover := &image{
-					external: true,
-					url:      notionPage.Cover.External.URL,
-				}
-
-				page.coverPhoto = cover
-			}
+			page.coverPhoto = coverImage(notionPage.Cover)
 
 			pages[i] = page
+			m.cache.Set(page.id, page)
 		}
 
-		m.pages = pages
-
 		return pages, nil
 	}
 	notionPage, err := m.notionClient.FindPageByID(context.Background(), m.config.PageID)
@@ -126,27 +366,21 @@ func (m *migrator) FetchPages(ctx context.Context) ([]*Page, error) {
 		)
 	}
 
-	var cover *image
-	if notionPage.Cover != nil {
-		cover = &image{
-			external: true,
-			url:      notionPage.Cover.External.URL,
-		}
-	}
+	cover := coverImage(notionPage.Cover)
 
-	title := m.extractPageTitle(notionPage)
+	pagePath := m.uniquePath(notionPage.ID, path.Join(m.config.VaultFilepath(), m.extractPageTitle(notionPage)))
 	pages := []*Page{
 		{
 			id:         notionPage.ID,
 			buffer:     &strings.Builder{},
-			title:      title,
-			Path:       path.Join(m.config.VaultFilepath(), title),
+			title:      m.vaultLink(pagePath),
+			Path:       pagePath,
 			notionPage: notionPage,
 			parent:     nil,
 			coverPhoto: cover,
 		},
 	}
-	m.pages = pages
+	m.cache.Set(pages[0].id, pages[0])
 
 	return pages, nil
 }
@@ -154,6 +388,14 @@ func (m *migrator) FetchPages(ctx context.Context) ([]*Page, error) {
 func (m *migrator) extractPageTitle(page notion.Page) string {
 	var str string
 
+	if m.pageNameTemplate != nil {
+		name, err := m.templatePageName(page)
+		if err == nil {
+			return m.sanitizePageName(name)
+		}
+		m.logger.Info(fmt.Sprintf("page %s keeps its title, the page name template failed. error: %s\n", page.ID, err))
+	}
+
 	switch page.Parent.Type {
 	case notion.ParentTypeDatabase:
 		properties, ok := page.Properties.(notion.DatabasePageProperties)
@@ -183,14 +425,11 @@ func (m *migrator) extractPageTitle(page notion.Page) string {
 			if ok {
 				switch value.Type {
 				case notion.DBPropTypeDate:
-					date := value.Date.Start
-					if val != "" {
-						str += timefmt.Format(date.Time, val)
-					} else {
-						str += date.Time.String()
+					if value.Date != nil {
+						str += m.titleDate(m.inTimeZone(*value.Date).Start, val)
 					}
 				case notion.DBPropTypeTitle:
-					str += extractPlainTextFromRichText(value.Title)
+					str += m.sanitizeName(extractPlainTextFromRichText(value.Title))
 				default:
 					m.logger.Info(fmt.Sprintf("type: `%s` for extracting page title not supported\n", value.Type))
 				}
@@ -199,7 +438,7 @@ func (m *migrator) extractPageTitle(page notion.Page) string {
 
 		// In the case we did not find any element to create the page title, we default to the title property
 		if str == "" {
-			str = extractPlainTextFromRichText(titleProperty.Title)
+			str = m.sanitizeName(extractPlainTextFromRichText(titleProperty.Title))
 		}
 	case notion.ParentTypeWorkspace:
 		fallthrough
@@ -211,20 +450,32 @@ func (m *migrator) extractPageTitle(page notion.Page) string {
 			m.logger.Error(fmt.Sprintf("expected PageProperties, got %T", page.Properties))
 			return "untitled.md"
 		}
-		str = extractPlainTextFromRichText(properties.Title.Title)
+		str = m.sanitizeName(extractPlainTextFromRichText(properties.Title.Title))
 	}
 
-	fileName := fmt.Sprintf("%s.md", str)
-	return fileName
+	// The slashes left come from the page name format, they create folders.
+	return m.sanitizePageName(str)
 }
 
-func (m *migrator) FetchParseAndSavePage(ctx context.Context, page *Page, pageProperties map[string]bool) error {
-	pageBlocks, err := m.notionClient.FindBlockChildrenByID(ctx, page.notionPage.ID, nil)
+func (m *migrator) FetchParseAndSavePage(
+	ctx context.Context,
+	page *Page,
+	pageProperties map[string]bool,
+) (err error) {
+	// A page with partial content is not written, so the next sync migrates it again.
+	defer func() {
+		if err != nil {
+			m.markFailed(page.id)
+		}
+	}()
+
+	pageBlocks, err := m.fetchBlockChildren(ctx, page.notionPage.ID)
 	if err != nil {
 		return fmt.Errorf("failed to extract children blocks for block ID %s. error: %w", page.notionPage.ID, err)
 	}
 
-	if page.notionPage.Parent.Type == notion.ParentTypeDatabase && len(pageProperties) > 0 {
+	migrateProperties := len(pageProperties) > 0 || len(m.config.PropertyMappings) > 0
+	if page.notionPage.Parent.Type == notion.ParentTypeDatabase && migrateProperties {
 		props, ok := page.notionPage.Properties.(notion.DatabasePageProperties)
 		if !ok {
 			return fmt.Errorf("expected DatabasePageProperties, got %T", page.notionPage.Properties)
@@ -232,12 +483,6 @@ func (m *migrator) FetchParseAndSavePage(ctx context.Context, page *Page, pagePr
 
 		frotmatterProps := make(notion.DatabasePageProperties)
 
-		allProps := false
-
-		if pageProperties["all"] {
-			allProps = true
-		}
-
 		sortedPropkeys := make([]string, 0, len(props))
 
 		for k := range props {
@@ -248,13 +493,16 @@ func (m *migrator) FetchParseAndSavePage(ctx context.Context, page *Page, pagePr
 
 		for _, propKey := range sortedPropkeys {
 			propValue := props[propKey]
-			if pageProperties[strings.ToLower(propKey)] || allProps {
+			if m.migrateProperty(propKey, pageProperties) {
 				frotmatterProps[propKey] = propValue
 			}
 		}
 
 		if len(frotmatterProps) > 0 {
-			m.propertiesToFrontMatter(ctx, page, sortedPropkeys, frotmatterProps, page.buffer)
+			err = m.propertiesToFrontMatter(ctx, page, sortedPropkeys, frotmatterProps, page.buffer)
+			if err != nil {
+				return fmt.Errorf("failed to convert page properties to frontmatter. error: %w", err)
+			}
 		}
 	}
 
@@ -263,7 +511,7 @@ func (m *migrator) FetchParseAndSavePage(ctx context.Context, page *Page, pagePr
 		page.buffer.WriteString("\n\n")
 	}
 
-	err = m.pageToMarkdown(ctx, page, pageBlocks.Results, false)
+	err = m.pageToMarkdown(ctx, page, pageBlocks)
 
 	if err != nil {
 		return fmt.Errorf("failed to convert page to markdown. error: %w", err)
@@ -272,13 +520,31 @@ func (m *migrator) FetchParseAndSavePage(ctx context.Context, page *Page, pagePr
 	return nil
 }
 
+func (m *migrator) markFailed(pageID string) {
+	m.failedMu.Lock()
+	defer m.failedMu.Unlock()
+
+	if m.failed == nil {
+		m.failed = map[string]bool{}
+	}
+	m.failed[pageID] = true
+}
+
+// hasFailed reports if the page could not be migrated.
+func (m *migrator) hasFailed(pageID string) bool {
+	m.failedMu.Lock()
+	defer m.failedMu.Unlock()
+
+	return m.failed[pageID]
+}
+
 func (m *migrator) DisplayInformation(_ context.Context) error {
 	buffer := bufio.NewWriter(os.Stdout)
 	for _, page := range m.pages {
 		if _, err := fmt.Fprintf(buffer, "%s \n", m.removeObsidianVault(page.Path)); err != nil {
 			return fmt.Errorf("failed to write page path: %w", err)
 		}
-		m.displayPageInfo(page, buffer, 0)
+		m.displayPageInfo(page, buffer, 0, map[*Page]bool{page: true})
 	}
 
 	if err := buffer.Flush(); err != nil {
@@ -291,7 +557,9 @@ func (m *migrator) DisplayInformation(_ context.Context) error {
 const spaceCount = 4
 const pageIndex = 2
 
-func (m *migrator) displayPageInfo(page *Page, buffer *bufio.Writer, index int) {
+// displayPageInfo prints the pages referenced by page.
+// visited holds the pages already printed in the current branch, so pages referencing each other are printed once.
+func (m *migrator) displayPageInfo(page *Page, buffer *bufio.Writer, index int, visited map[*Page]bool) {
 	var spaces int
 	if index > 0 {
 		spaces = index * spaceCount
@@ -302,7 +570,12 @@ func (m *migrator) displayPageInfo(page *Page, buffer *bufio.Writer, index int)
 		if _, err := fmt.Fprintf(buffer, "%*s %s\n", spaces, "|->", m.removeObsidianVault(childPage.Path)); err != nil {
 			m.logger.Error(fmt.Sprintf("failed to write child page path: %v", err))
 		}
-		m.displayPageInfo(childPage, buffer, pageIndex)
+		if visited[childPage] {
+			continue
+		}
+		visited[childPage] = true
+		m.displayPageInfo(childPage, buffer, pageIndex, visited)
+		delete(visited, childPage)
 	}
 }
 
@@ -311,44 +584,65 @@ func (m *migrator) removeObsidianVault(s string) string {
 }
 
 func (m *migrator) WritePagesToDisk(_ context.Context) error {
+	// Pages can be referenced from many pages, we only write them once.
+	written := map[string]*Page{}
+
+	m.resolveSyncedCopies()
+
 	for _, page := range m.pages {
-		err := m.writePage(page)
+		err := m.writePage(page, written)
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return m.removeDeletedPages(written)
 }
 
-func (m *migrator) writePage(page *Page) error {
-	if err := os.MkdirAll(filepath.Dir(page.Path), 0750); err != nil {
-		return fmt.Errorf("failed to create the necessary directories in for the Obsidian vault.  error: %w", err)
+func (m *migrator) writePage(page *Page, written map[string]*Page) error {
+	if _, ok := written[page.Path]; ok {
+		return nil
 	}
+	written[page.Path] = page
 
-	f, err := os.Create(page.Path)
-	if err != nil {
-		return fmt.Errorf("failed to create the markdown file %s. error: %w", path.Base(page.Path), err)
+	if page.unchanged {
+		return nil
 	}
 
-	defer f.Close()
+	// The previous note and state entry of a failed page are kept, the child pages migrated are written.
+	if m.hasFailed(page.id) {
+		m.logger.Warn(fmt.Sprintf("not writing %s, the page could not be migrated\n", m.removeObsidianVault(page.Path)))
+		return m.writeChildPages(page, written)
+	}
 
 	output := page.buffer.String()
 
-	_, err = f.WriteString(output)
+	changed, err := m.recordPage(page, output)
 	if err != nil {
 		return err
 	}
 
-	if m.config.StoreImages {
-		for _, image := range page.images {
-			err := m.downloadImage(image.name, image.url)
+	if changed {
+		if err = writeFile(page.Path, output); err != nil {
 			return err
 		}
 	}
 
+	// Images can not be downloaded without network, the images downloaded by previous runs are kept.
+	if m.config.StoreImages && !m.config.Offline {
+		for _, image := range page.images {
+			if err = m.downloadImage(image.name, image.url); err != nil {
+				return err
+			}
+		}
+	}
+
+	return m.writeChildPages(page, written)
+}
+
+func (m *migrator) writeChildPages(page *Page, written map[string]*Page) error {
 	for _, childPage := range page.children {
-		childErr := m.writePage(childPage)
+		childErr := m.writePage(childPage, written)
 		if childErr != nil {
 			return childErr
 		}
@@ -357,6 +651,23 @@ func (m *migrator) writePage(page *Page) error {
 	return nil
 }
 
+func writeFile(pagePath, content string) error {
+	if err := os.MkdirAll(filepath.Dir(pagePath), 0750); err != nil {
+		return fmt.Errorf("failed to create the necessary directories in for the Obsidian vault.  error: %w", err)
+	}
+
+	f, err := os.Create(pagePath)
+	if err != nil {
+		return fmt.Errorf("failed to create the markdown file %s. error: %w", path.Base(pagePath), err)
+	}
+
+	defer f.Close()
+
+	_, err = f.WriteString(content)
+
+	return err
+}
+
 const Untitled = "Untitled"
 
 // fetchPage check if the page has been extracted before avoiding doing to many queries to notion.so
@@ -384,7 +695,7 @@ func (m *migrator) handlePageParent(
 			if err != nil {
 				return "", fmt.Errorf("failed to find parent db %s: %w", mentionPage.Parent.DatabaseID, err)
 			}
-			dbTitle := extractPlainTextFromRichText(dbPage.Title)
+			dbTitle := m.sanitizeName(extractPlainTextFromRichText(dbPage.Title))
 			childTitle = path.Join(dbTitle, childTitle)
 		}
 	case notion.ParentTypeBlock:
@@ -423,7 +734,7 @@ func (m *migrator) fetchPage(
 	cached, ok := m.cache.Get(pageID)
 	if ok {
 		debugLog := fmt.Sprintf("cached page found %s\n", cached)
-		if cached.parent != parentPage {
+		if cached.parent != parentPage && cached != parentPage {
 			debugLog += fmt.Sprintf("adding to parent %s\n", parentPage)
 			parentPage.children = append(parentPage.children, cached)
 		}
@@ -453,11 +764,8 @@ func (m *migrator) fetchPage(
 	// to avoid endless loop. In this case we just want to get the page title
 	// TODO: Check if we need this logic
 	if m.cache.IsWorking(pageID) {
-		childTitle := title
-		extractTitle := false
-		if childTitle == "" {
-			extractTitle = true
-		}
+		childTitle := m.mentionTitle(title)
+		extractTitle := childTitle == ""
 		mentionPage, err := m.notionClient.FindPageByID(ctx, pageID)
 		if err != nil {
 			return fmt.Errorf("failed to find page %s: %w", pageID, err)
@@ -467,6 +775,8 @@ func (m *migrator) fetchPage(
 		if err != nil {
 			return err
 		}
+		// The page being fetched claimed the same path, the link uses its final name.
+		childTitle = m.vaultLink(m.uniquePath(pageID, path.Join(m.config.VaultFilepath(), childTitle)))
 
 		var result string
 		if quotes {
@@ -493,13 +803,8 @@ func (m *migrator) fetchPage(
 		}
 	}()
 
-	extractTitle := false
-	childTitle := title
-	if childTitle == "" {
-		extractTitle = true
-	} else if !strings.HasSuffix(childTitle, ".md") {
-		childTitle = fmt.Sprintf("%s.md", childTitle)
-	}
+	childTitle := m.mentionTitle(title)
+	extractTitle := childTitle == ""
 
 	mentionPage, err := m.notionClient.FindPageByID(ctx, pageID)
 	if err != nil {
@@ -515,25 +820,23 @@ func (m *migrator) fetchPage(
 		return fmt.Errorf("unable to find page information %s", pageID)
 	}
 
+	pagePath := m.uniquePath(pageID, path.Join(m.config.VaultFilepath(), childTitle))
+	childTitle = m.vaultLink(pagePath)
+
 	newPage = &Page{
 		id:         pageID,
 		notionPage: mentionPage,
 		buffer:     &strings.Builder{},
 		parent:     parentPage,
 		title:      childTitle,
-		Path:       path.Join(m.config.VaultFilepath(), childTitle),
+		Path:       pagePath,
 	}
 
-	if mentionPage.Cover != nil {
-		newPage.coverPhoto = &image{
-			external: true,
-			url:      mentionPage.Cover.External.URL,
-		}
-	}
+	newPage.coverPhoto = coverImage(mentionPage.Cover)
 
 	parentPage.children = append(parentPage.children, newPage)
 
-	if err = m.FetchParseAndSavePage(ctx, newPage, m.config.PagePropertiesToMigrate); err != nil {
+	if err = m.fetchPageContent(ctx, newPage); err != nil {
 		m.logger.Info(fmt.Sprintf("failed to fetch mention page content with page parent: %s\n", childTitle))
 		return err
 	}
@@ -550,8 +853,182 @@ func (m *migrator) fetchPage(
 	return nil
 }
 
-func (m *migrator) fetchNotionDBPages(ctx context.Context) ([]notion.Page, error) {
-	notionResponse, err := m.notionClient.QueryDatabase(ctx, m.config.DatabaseID, nil)
+// mentionTitle returns the note name for the title of a mention.
+// It is empty when the title has to be extracted from the page.
+func (m *migrator) mentionTitle(title string) string {
+	if title == "" {
+		return ""
+	}
+
+	return m.sanitizePageName(m.sanitizeName(strings.TrimSuffix(title, ".md")))
+}
+
+// migrateChildren reports if the child pages and databases of page have to be migrated.
+// When migrating the whole workspace the children are already migrated, so they are always linked.
+func (m *migrator) migrateChildren(page *Page) bool {
+	if m.config.Workspace {
+		return true
+	}
+
+	return m.config.Recursive && (m.config.RecursiveDepth == 0 || page.depth < m.config.RecursiveDepth)
+}
+
+// childrenFolder returns the folder for the child pages and databases of page,
+// mirroring the Notion hierarchy: the children of `Projects.md` are stored in `Projects/`.
+func childrenFolder(page *Page) string {
+	return strings.TrimSuffix(page.Path, filepath.Ext(page.Path))
+}
+
+// vaultLink returns the wikilink target for a page stored in pagePath.
+func (m *migrator) vaultLink(pagePath string) string {
+	return strings.TrimPrefix(pagePath, m.config.VaultFilepath()+"/")
+}
+
+// fetchChildPage migrates a child page block. The ID of the block is the ID of the child page.
+// The child page is stored in a folder named after its parent.
+func (m *migrator) fetchChildPage(ctx context.Context, parentPage *Page, pageID string, buffer *strings.Builder) error {
+	if cached, ok := m.cache.Get(pageID); ok {
+		if cached.parent != parentPage && cached != parentPage {
+			parentPage.children = append(parentPage.children, cached)
+		}
+		fmt.Fprintf(buffer, "[[%s]]", cached.title)
+		return nil
+	}
+
+	notionPage, err := m.notionClient.FindPageByID(ctx, pageID)
+	if err != nil {
+		return fmt.Errorf("failed to find child page %s: %w", pageID, err)
+	}
+
+	childPage := m.newChildPage(parentPage, notionPage, childrenFolder(parentPage))
+
+	if err = m.fetchPageContent(ctx, childPage); err != nil {
+		return fmt.Errorf("failed to migrate child page %s: %w", pageID, err)
+	}
+
+	fmt.Fprintf(buffer, "[[%s]]", childPage.title)
+
+	return nil
+}
+
+// fetchChildDatabase migrates every page of a child database block. The ID of the block is the ID of the database.
+// The database pages are stored in a folder named after the database, inside the folder of the parent page.
+// The database is rendered as its title followed by a list of links to its pages.
+func (m *migrator) fetchChildDatabase(
+	ctx context.Context,
+	parentPage *Page,
+	databaseID string,
+	buffer *strings.Builder,
+) error {
+	db := m.childDatabase(databaseID)
+	db.once.Do(func() {
+		notionDB, err := m.notionClient.FindDatabaseByID(ctx, databaseID)
+		if err != nil {
+			db.err = fmt.Errorf("failed to find child database %s: %w", databaseID, err)
+			return
+		}
+
+		db.pages, err = m.fetchNotionDBPages(ctx, databaseID, nil)
+		if err != nil {
+			db.err = fmt.Errorf("failed to get pages from child database %s: %w", databaseID, err)
+			return
+		}
+
+		db.title = m.sanitizeName(extractPlainTextFromRichText(notionDB.Title))
+	})
+	if db.err != nil {
+		return db.err
+	}
+
+	notionPages := db.pages
+	folder := path.Join(childrenFolder(parentPage), db.title)
+
+	fmt.Fprintf(buffer, "%s\n", db.title)
+
+	// Pages with the same name are told apart by their ID, the oldest page keeps the name.
+	for _, notionPage := range creationOrder(notionPages) {
+		if _, ok := m.cache.Get(notionPage.ID); !ok {
+			m.uniquePath(notionPage.ID, path.Join(folder, m.extractPageTitle(notionPage)))
+		}
+	}
+
+	for _, notionPage := range notionPages {
+		if cached, ok := m.cache.Get(notionPage.ID); ok {
+			if cached.parent != parentPage && cached != parentPage {
+				parentPage.children = append(parentPage.children, cached)
+			}
+			fmt.Fprintf(buffer, "- [[%s]]\n", cached.title)
+			continue
+		}
+
+		childPage := m.newChildPage(parentPage, notionPage, folder)
+
+		if err := m.fetchPageContent(ctx, childPage); err != nil {
+			return fmt.Errorf("failed to migrate page %s from child database %s: %w", notionPage.ID, databaseID, err)
+		}
+
+		fmt.Fprintf(buffer, "- [[%s]]\n", childPage.title)
+	}
+
+	return nil
+}
+
+// childDatabase is a database shown in a page, with the pages it had when it was fetched.
+type childDatabase struct {
+	once  sync.Once
+	title string
+	pages []notion.Page
+	err   error
+}
+
+// childDatabase returns the child database with the ID, to be fetched when its once has not run yet.
+func (m *migrator) childDatabase(databaseID string) *childDatabase {
+	m.childDatabasesMu.Lock()
+	defer m.childDatabasesMu.Unlock()
+
+	if m.childDatabases == nil {
+		m.childDatabases = map[string]*childDatabase{}
+	}
+
+	db, ok := m.childDatabases[databaseID]
+	if !ok {
+		db = &childDatabase{}
+		m.childDatabases[databaseID] = db
+	}
+
+	return db
+}
+
+// newChildPage creates a child page of parentPage stored in folder
+// and saves it in the cache before fetching its content, so links back to it do not fetch it again.
+func (m *migrator) newChildPage(parentPage *Page, notionPage notion.Page, folder string) *Page {
+	pagePath := m.uniquePath(notionPage.ID, path.Join(folder, m.extractPageTitle(notionPage)))
+
+	childPage := &Page{
+		id:         notionPage.ID,
+		buffer:     &strings.Builder{},
+		title:      m.vaultLink(pagePath),
+		Path:       pagePath,
+		notionPage: notionPage,
+		parent:     parentPage,
+		depth:      parentPage.depth + 1,
+	}
+
+	childPage.coverPhoto = coverImage(notionPage.Cover)
+
+	m.cache.Set(notionPage.ID, childPage)
+	parentPage.children = append(parentPage.children, childPage)
+
+	return childPage
+}
+
+// fetchNotionDBPages returns every page of a database matching the query, a nil query returns all of them.
+func (m *migrator) fetchNotionDBPages(
+	ctx context.Context,
+	databaseID string,
+	query *notion.DatabaseQuery,
+) ([]notion.Page, error) {
+	notionResponse, err := m.notionClient.QueryDatabase(ctx, databaseID, query)
 	if err != nil {
 		return []notion.Page{}, err
 	}
@@ -560,11 +1037,15 @@ func (m *migrator) fetchNotionDBPages(ctx context.Context) ([]notion.Page, error
 
 	result = append(result, notionResponse.Results...)
 
-	query := &notion.DatabaseQuery{}
+	// The next pages keep the filter and the sorts of the query.
+	next := notion.DatabaseQuery{}
+	if query != nil {
+		next = *query
+	}
 	for notionResponse.HasMore {
-		query.StartCursor = *notionResponse.NextCursor
+		next.StartCursor = *notionResponse.NextCursor
 
-		notionResponse, err = m.notionClient.QueryDatabase(ctx, m.config.DatabaseID, query)
+		notionResponse, err = m.notionClient.QueryDatabase(ctx, databaseID, &next)
 		if err != nil {
 			return []notion.Page{}, err
 		}
@@ -575,6 +1056,33 @@ func (m *migrator) fetchNotionDBPages(ctx context.Context) ([]notion.Page, error
 	return result, nil
 }
 
+// fetchBlockChildren returns every child block of blockID.
+// The Notion API returns at most 100 blocks per request, so we follow the cursor until there are no more results.
+func (m *migrator) fetchBlockChildren(ctx context.Context, blockID string) ([]notion.Block, error) {
+	notionResponse, err := m.notionClient.FindBlockChildrenByID(ctx, blockID, nil)
+	if err != nil {
+		return []notion.Block{}, err
+	}
+
+	result := []notion.Block{}
+
+	result = append(result, notionResponse.Results...)
+
+	query := &notion.PaginationQuery{}
+	for notionResponse.HasMore && notionResponse.NextCursor != nil {
+		query.StartCursor = *notionResponse.NextCursor
+
+		notionResponse, err = m.notionClient.FindBlockChildrenByID(ctx, blockID, query)
+		if err != nil {
+			return []notion.Block{}, err
+		}
+
+		result = append(result, notionResponse.Results...)
+	}
+
+	return result, nil
+}
+
 func (m *migrator) downloadImage(name, url string) error {
 	imageLocation := filepath.Join(m.config.VaultImagePath(), name)
 	if err := os.MkdirAll(path.Dir(imageLocation), 0750); err != nil {
@@ -15,6 +15,7 @@ import (
 
 	"github.com/GustavoCaso/n2o/internal/config"
 	"github.com/GustavoCaso/n2o/internal/log"
+	"github.com/GustavoCaso/n2o/internal/notiontest"
 	"github.com/dstotijn/go-notion"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -89,6 +90,53 @@ func TestFetchPages(t *testing.T) {
 				assert.Nil(t, p.parent)
 			},
 		},
+		{
+			name: "with workspace",
+			config: &config.Config{
+				Workspace:        true,
+				VaultPath:        "vault",
+				VaultDestination: "notion",
+			},
+			statusCode: 200,
+			respBody: func(r *http.Request) io.Reader {
+				switch r.URL.String() {
+				case "https://api.notion.com/v1/search":
+					body, err := io.ReadAll(r.Body)
+					if err != nil {
+						panic(err)
+					}
+					if strings.Contains(string(body), "cursor-search-2") {
+						return bytes.NewReader(mustReadFixture("fixtures/workspace/search_2.json"))
+					}
+					return bytes.NewReader(mustReadFixture("fixtures/workspace/search_1.json"))
+				case "https://api.notion.com/v1/blocks/a1000000-0000-0000-0000-000000000006":
+					return bytes.NewReader(mustReadFixture("fixtures/workspace/column_block.json"))
+				default:
+					panic(fmt.Sprintf("unhandled URL: %s", r.URL.String()))
+				}
+			},
+			assertions: func(t *testing.T, pages []*Page) {
+				paths := map[string]string{}
+				for _, page := range pages {
+					paths[page.id] = page.Path
+				}
+
+				assert.Equal(t, map[string]string{
+					"a1000000-0000-0000-0000-000000000001": "vault/notion/Home.md",
+					"a1000000-0000-0000-0000-000000000002": "vault/notion/Home/Projects.md",
+					"a1000000-0000-0000-0000-000000000004": "vault/notion/Home/Projects/Tasks/Write docs.md",
+					"a1000000-0000-0000-0000-000000000005": "vault/notion/Home/Notes.md",
+					"a1000000-0000-0000-0000-000000000008": "vault/notion/Shared page.md",
+				}, paths)
+
+				for _, page := range pages {
+					if page.id == "a1000000-0000-0000-0000-000000000004" {
+						assert.Equal(t, "Home/Projects/Tasks/Write docs.md", page.title)
+						assert.Equal(t, "a1000000-0000-0000-0000-000000000002", page.parent.id)
+					}
+				}
+			},
+		},
 		{
 			name: "with page ID and error",
 			config: &config.Config{
@@ -122,7 +170,7 @@ func TestFetchPages(t *testing.T) {
 				notionClient: notionClient,
 				config:       test.config,
 				logger:       logger,
-				cache:        nil,
+				cache:        NewCache(),
 			}
 
 			pages, err := migrator.FetchPages(context.TODO())
@@ -192,7 +240,7 @@ func TestExtractPageTitle(t *testing.T) {
 					},
 				},
 			},
-			expected: "2021/05/18 12:49:00.md",
+			expected: "2021/05/18 12-49-00.md",
 		},
 		{
 			name: "with database and date title and no custom format",
@@ -216,7 +264,33 @@ func TestExtractPageTitle(t *testing.T) {
 					},
 				},
 			},
-			expected: "2021-05-18 12:49:00 -0500 -0500.md",
+			expected: "2021-05-18.md",
+		},
+		{
+			name: "with database and date title, default date format and time zone",
+			config: &config.Config{
+				DatabaseID: "0000",
+				PageNameFilters: map[string]string{
+					"date": "",
+				},
+				DateFormat: "%d.%m.%Y %H:%M",
+				TimeZone:   mustLoadLocation("Asia/Tokyo"),
+			},
+			page: notion.Page{
+				Parent: notion.Parent{
+					Type: notion.ParentTypeDatabase,
+				},
+				Properties: notion.DatabasePageProperties{
+					"Date": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeDate,
+						Name: "Date",
+						Date: &notion.Date{
+							Start: parseDateTime("2021-05-18T12:49:00.000-05:00"),
+						},
+					},
+				},
+			},
+			expected: "19.05.2021 02-49.md",
 		},
 		{
 			name: "with page",
@@ -305,6 +379,153 @@ func TestExtractPageTitle(t *testing.T) {
 			},
 			expected: "Hello.md",
 		},
+		{
+			name: "with database and title with characters not allowed in file names",
+			config: &config.Config{
+				DatabaseID: "0000",
+				PageNameFilters: map[string]string{
+					"date":  "%Y/%m",
+					"title": "",
+				},
+			},
+			page: notion.Page{
+				Parent: notion.Parent{
+					Type: notion.ParentTypeDatabase,
+				},
+				Properties: notion.DatabasePageProperties{
+					"Title": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeTitle,
+						Title: []notion.RichText{
+							{
+								PlainText: " Q&A: what/why? [draft] ",
+							},
+						},
+					},
+					"Date": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeDate,
+						Name: "Date",
+						Date: &notion.Date{
+							Start: parseDateTime("2021-05-18T12:49:00.000-05:00"),
+						},
+					},
+				},
+			},
+			expected: "2021/05Q&A- what-why (draft).md",
+		},
+		{
+			name: "with database and page name template",
+			config: &config.Config{
+				DatabaseID:       "0000",
+				PageNameTemplate: `{{.Status}}/{{date .Due "%Y-%m"}} - {{.Name | truncate 9}}`,
+			},
+			page: notion.Page{
+				Parent: notion.Parent{
+					Type: notion.ParentTypeDatabase,
+				},
+				Properties: notion.DatabasePageProperties{
+					"Name": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeTitle,
+						Title: []notion.RichText{
+							{
+								PlainText: "Write the quarterly report",
+							},
+						},
+					},
+					"Status": notion.DatabasePageProperty{
+						Type:   notion.DBPropTypeStatus,
+						Status: &notion.SelectOptions{Name: "Done"},
+					},
+					"Due": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeDate,
+						Date: &notion.Date{
+							Start: parseDateTime("2021-05-18T12:49:00.000-05:00"),
+						},
+					},
+				},
+			},
+			expected: "Done/2021-05 - Write the.md",
+		},
+		{
+			name: "with database and page name template using slug, upper and join",
+			config: &config.Config{
+				DatabaseID:       "0000",
+				PageNameTemplate: `{{upper .Priority}} {{join "+" .Tags}} {{slug .Name}} {{.Score}}`,
+			},
+			page: notion.Page{
+				Parent: notion.Parent{
+					Type: notion.ParentTypeDatabase,
+				},
+				Properties: notion.DatabasePageProperties{
+					"Name": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeTitle,
+						Title: []notion.RichText{
+							{
+								PlainText: "Q&A Session",
+							},
+						},
+					},
+					"Priority": notion.DatabasePageProperty{
+						Type:   notion.DBPropTypeSelect,
+						Select: &notion.SelectOptions{Name: "high"},
+					},
+					"Tags": notion.DatabasePageProperty{
+						Type:        notion.DBPropTypeMultiSelect,
+						MultiSelect: []notion.SelectOptions{{Name: "work"}, {Name: "meeting"}},
+					},
+					"Score": notion.DatabasePageProperty{
+						Type:   notion.DBPropTypeNumber,
+						Number: notion.Float64Ptr(3),
+					},
+				},
+			},
+			expected: "HIGH work+meeting q-a-session 3.md",
+		},
+		{
+			name: "with database and page name template using a missing property",
+			config: &config.Config{
+				DatabaseID:       "0000",
+				PageNameTemplate: `{{.Status}} - {{.Name}}`,
+			},
+			page: notion.Page{
+				Parent: notion.Parent{
+					Type: notion.ParentTypeDatabase,
+				},
+				Properties: notion.DatabasePageProperties{
+					"Name": notion.DatabasePageProperty{
+						Type: notion.DBPropTypeTitle,
+						Title: []notion.RichText{
+							{
+								PlainText: "Hello",
+							},
+						},
+					},
+				},
+			},
+			expected: "Hello.md",
+		},
+		{
+			name: "with page and page name template",
+			config: &config.Config{
+				PageID:           "0000",
+				PageNameTemplate: `{{date .CreatedTime "%Y"}} {{.Title}}`,
+			},
+			page: notion.Page{
+				CreatedTime: time.Date(2021, 5, 18, 12, 49, 0, 0, time.UTC),
+				Parent: notion.Parent{
+					Type: notion.ParentTypePage,
+				},
+				Properties: notion.PageProperties{
+					Title: notion.PageTitle{
+						Title: []notion.RichText{
+							{
+								PlainText: "Hello",
+							},
+						},
+					},
+				},
+			},
+			expected: "2021 Hello.md",
+		},
 	}
 
 	for _, test := range tests {
@@ -318,6 +539,10 @@ func TestExtractPageTitle(t *testing.T) {
 				logger:       logger,
 			}
 
+			var err error
+			migrator.pageNameTemplate, err = migrator.parsePageNameTemplate()
+			require.NoError(t, err)
+
 			value := migrator.extractPageTitle(test.page)
 			assert.Equal(t, test.expected, value)
 		})
@@ -392,19 +617,27 @@ func TestFetchParseAndSavePage_WritePagesToDisk(t *testing.T) {
 				"lasteditedby":   true,
 				"relation":       true,
 				"rollup":         true,
+				"people":         true,
+				"files":          true,
 			},
 			expected: `---
-Age: 34.000000
+Age: 34
+Calculation: 42
 Checkbox: true
 CreatedBy: Jane Doe
-CreatedTime: 2021-05-24 15:44:09.123 +0000 UTC
+CreatedTime: 2021-05-24T15:44:09Z
 Email: jane@example.com
+Files:
+  - foobar.pdf
 LastEditedBy: Jane Doe
-LastEditedTime: 2021-05-24 15:44:09.123 +0000 UTC
+LastEditedTime: 2021-05-24T15:44:09Z
+People:
+  - Jane Doe
 PhoneNumber: 867-5309
-Relation: 
-  - 
-Rollup: [42.000000 10.000000]
+Relation: []
+Rollup:
+  - 42
+  - 10
 Title: Hello
 URL: https://example.com
 ---
@@ -652,6 +885,20 @@ URL: https://example.com
 					return readFixture("fixtures/page_blocks_with_children/children_blocks_2.json")
 				case "https://api.notion.com/v1/blocks/117a3598-a993-81f0-8694-ed4cd7b32974/children":
 					return readFixture("fixtures/page_blocks_with_children/children_blocks_3.json")
+				case "https://api.notion.com/v1/blocks/117a3598-a993-8200-8000-000000000002/children":
+					return readFixture("fixtures/page_blocks_with_children/children_blocks_4.json")
+				case "https://api.notion.com/v1/blocks/117a3598-a993-8200-8000-000000000005/children":
+					return readFixture("fixtures/page_blocks_with_children/children_blocks_5.json")
+				case "https://api.notion.com/v1/blocks/117a3598-a993-8200-8000-000000000007/children":
+					return readFixture("fixtures/page_blocks_with_children/children_blocks_6.json")
+				case "https://api.notion.com/v1/blocks/117a3598-a993-8200-8000-000000000008/children":
+					return readFixture("fixtures/page_blocks_with_children/children_blocks_7.json")
+				case "https://api.notion.com/v1/blocks/117a3598-a993-8200-8000-000000000006/children":
+					return readFixture("fixtures/page_blocks_with_children/children_blocks_8.json")
+				case "https://api.notion.com/v1/blocks/117a3598-a993-8200-8000-000000000011/children":
+					return readFixture("fixtures/page_blocks_with_children/children_blocks_9.json")
+				case "https://api.notion.com/v1/blocks/117a3598-a993-8200-8000-000000000003/children":
+					return readFixture("fixtures/page_blocks_with_children/children_blocks_10.json")
 				default:
 					panic(fmt.Sprintf("unhandled URL: %s", r.URL.String()))
 				}
@@ -675,6 +922,123 @@ URL: https://example.com
 				SaveToDisk:  true,
 			},
 		},
+		{
+			name:       "page with more than one page of block children",
+			statusCode: 200,
+			notionRespBody: func(r *http.Request) io.Reader {
+				readFixture := func(path string) io.Reader {
+					f := mustReadFixture(path)
+					return bytes.NewReader(f)
+				}
+
+				switch r.URL.String() {
+				case "https://api.notion.com/v1/blocks/1/children":
+					return readFixture("fixtures/page_blocks_paginated/page_blocks_1.json")
+				case "https://api.notion.com/v1/blocks/1/children?start_cursor=cursor-page-2":
+					return readFixture("fixtures/page_blocks_paginated/page_blocks_2.json")
+				case "https://api.notion.com/v1/blocks/1/children?start_cursor=cursor-page-3":
+					return readFixture("fixtures/page_blocks_paginated/page_blocks_3.json")
+				case "https://api.notion.com/v1/blocks/b0000000-0000-0000-0000-000000000004/children":
+					return readFixture("fixtures/page_blocks_paginated/children_blocks_1.json")
+				case "https://api.notion.com/v1/blocks/b0000000-0000-0000-0000-000000000004/children?start_cursor=cursor-children-2":
+					return readFixture("fixtures/page_blocks_paginated/children_blocks_2.json")
+				case "https://api.notion.com/v1/blocks/b0000000-0000-0000-0000-000000000005/children":
+					return readFixture("fixtures/page_blocks_paginated/table_rows_1.json")
+				case "https://api.notion.com/v1/blocks/b0000000-0000-0000-0000-000000000005/children?start_cursor=cursor-rows-2":
+					return readFixture("fixtures/page_blocks_paginated/table_rows_2.json")
+				default:
+					panic(fmt.Sprintf("unhandled URL: %s", r.URL.String()))
+				}
+			},
+			buildPages: func(path string) []*Page {
+				return []*Page{
+					{
+						id:         "1",
+						buffer:     &strings.Builder{},
+						notionPage: notion.Page{ID: "1"},
+						parent:     nil,
+						Path:       filepath.Join(path, "example.md"),
+					},
+				}
+			},
+			pageProperties: map[string]bool{},
+			expected:       string(mustReadFixture("fixtures/page_blocks_paginated/result")),
+			config: &config.Config{
+				SaveToDisk: true,
+			},
+		},
+		{
+			name:           "recursive migration of child pages and child databases",
+			statusCode:     200,
+			notionRespBody: childPagesFixtures,
+			buildPages: func(path string) []*Page {
+				return []*Page{
+					{
+						id:         "1",
+						buffer:     &strings.Builder{},
+						notionPage: notion.Page{ID: "1"},
+						parent:     nil,
+						title:      "example.md",
+						Path:       filepath.Join(path, "example.md"),
+					},
+				}
+			},
+			customAssertions: func(t *testing.T, path string) {
+				content, err := os.ReadFile(filepath.Join(path, "example", "Child page.md"))
+				require.NoError(t, err)
+				assert.Equal(t, string(mustReadFixture("fixtures/child_pages/child_page_result")), string(content))
+
+				content, err = os.ReadFile(filepath.Join(path, "example", "Child page", "Grandchild page.md"))
+				require.NoError(t, err)
+				assert.Equal(t, "Grandchild content\n", string(content))
+
+				content, err = os.ReadFile(filepath.Join(path, "example", "Tasks", "Write docs.md"))
+				require.NoError(t, err)
+				assert.Equal(t, "Task content\n", string(content))
+			},
+			pageProperties: map[string]bool{},
+			expected:       string(mustReadFixture("fixtures/child_pages/result")),
+			config: &config.Config{
+				SaveToDisk: true,
+				Recursive:  true,
+			},
+		},
+		{
+			name:           "recursive migration stops at the configured depth",
+			statusCode:     200,
+			notionRespBody: childPagesFixtures,
+			buildPages: func(path string) []*Page {
+				return []*Page{
+					{
+						id:         "1",
+						buffer:     &strings.Builder{},
+						notionPage: notion.Page{ID: "1"},
+						parent:     nil,
+						title:      "example.md",
+						Path:       filepath.Join(path, "example.md"),
+					},
+				}
+			},
+			customAssertions: func(t *testing.T, path string) {
+				content, err := os.ReadFile(filepath.Join(path, "example", "Child page.md"))
+				require.NoError(t, err)
+				assert.Equal(
+					t,
+					string(mustReadFixture("fixtures/child_pages/child_page_result_with_depth")),
+					string(content),
+				)
+
+				_, err = os.Stat(filepath.Join(path, "example", "Child page", "Grandchild page.md"))
+				assert.True(t, os.IsNotExist(err))
+			},
+			pageProperties: map[string]bool{},
+			expected:       string(mustReadFixture("fixtures/child_pages/result")),
+			config: &config.Config{
+				SaveToDisk:     true,
+				Recursive:      true,
+				RecursiveDepth: 1,
+			},
+		},
 		{
 			name:       "page with cover photo",
 			statusCode: 200,
@@ -876,6 +1240,62 @@ func TestFetchParseAndSavePage_DryRun(t *testing.T) {
 	}
 }
 
+// TestMigrate_ReplayCassettes migrates the cassettes recorded with `-record`.
+// To reproduce a bug report, add the attached cassette to fixtures/cassettes with the expected notes.
+func TestMigrate_ReplayCassettes(t *testing.T) {
+	tests := []struct {
+		name     string
+		cassette string
+		config   *config.Config
+		expected map[string]string
+	}{
+		{
+			name:     "single page",
+			cassette: "fixtures/cassettes/page.jsonl",
+			config: &config.Config{
+				PageID: "000000",
+			},
+			expected: map[string]string{
+				"Lorem ipsum.md": `## Lacinato kale
+[Lacinato kale is a variety of kale with a long tradition in Italian cuisine, especially that of Tuscany. It is also known as Tuscan kale, Italian kale, dinosaur kale, kale, flat back kale, palm tree kale, or black Tuscan palm.](https://en.wikipedia.org/wiki/Lacinato_kale)
+`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.config.VaultPath = t.TempDir()
+
+			logger, _ := log.MockLogger()
+			m, err := NewMigrator(
+				test.config,
+				NewCache(),
+				logger,
+				WithNotionHTTPClient(notiontest.NewReplayClient(t, test.cassette)),
+			)
+			require.NoError(t, err)
+
+			ctx := context.TODO()
+
+			pages, err := m.FetchPages(ctx)
+			require.NoError(t, err)
+
+			for _, page := range pages {
+				require.NoError(t, m.FetchParseAndSavePage(ctx, page, test.config.PagePropertiesToMigrate))
+			}
+
+			require.NoError(t, m.WritePagesToDisk(ctx))
+
+			for notePath, expected := range test.expected {
+				content, err := os.ReadFile(filepath.Join(test.config.VaultPath, notePath))
+				require.NoError(t, err)
+				assert.Equal(t, expected, string(content))
+			}
+		})
+	}
+}
+
 func TestWriteRichText_Annotations(t *testing.T) {
 	migrator := migrator{
 		notionClient: nil,
@@ -1088,6 +1508,34 @@ func TestWriteRichText_Annotations(t *testing.T) {
 	}
 }
 
+func childPagesFixtures(r *http.Request) io.Reader {
+	readFixture := func(path string) io.Reader {
+		f := mustReadFixture(path)
+		return bytes.NewReader(f)
+	}
+
+	switch r.URL.String() {
+	case "https://api.notion.com/v1/blocks/1/children":
+		return readFixture("fixtures/child_pages/page_blocks.json")
+	case "https://api.notion.com/v1/pages/e0000000-0000-0000-0000-000000000001":
+		return readFixture("fixtures/child_pages/child_page.json")
+	case "https://api.notion.com/v1/blocks/e0000000-0000-0000-0000-000000000001/children":
+		return readFixture("fixtures/child_pages/child_page_blocks.json")
+	case "https://api.notion.com/v1/pages/e0000000-0000-0000-0000-000000000002":
+		return readFixture("fixtures/child_pages/grandchild_page.json")
+	case "https://api.notion.com/v1/blocks/e0000000-0000-0000-0000-000000000002/children":
+		return readFixture("fixtures/child_pages/grandchild_page_blocks.json")
+	case "https://api.notion.com/v1/databases/e0000000-0000-0000-0000-000000000003":
+		return readFixture("fixtures/child_pages/child_database.json")
+	case "https://api.notion.com/v1/databases/e0000000-0000-0000-0000-000000000003/query":
+		return readFixture("fixtures/child_pages/child_database_query.json")
+	case "https://api.notion.com/v1/blocks/e0000000-0000-0000-0000-000000000004/children":
+		return readFixture("fixtures/child_pages/database_page_blocks.json")
+	default:
+		panic(fmt.Sprintf("unhandled URL: %s", r.URL.String()))
+	}
+}
+
 func parseDateTime(value string) notion.DateTime {
 	dt, err := notion.ParseDateTime(value)
 	if err != nil {
@@ -1125,3 +1573,11 @@ func captureStdout(f func() error) (string, error) {
 	out, _ := io.ReadAll(r)
 	return string(out), err
 }
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
@@ -0,0 +1,377 @@
+package migrator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/itchyny/timefmt-go"
+)
+
+// Page fields available in the page name templates, next to the page properties.
+// A page property with the same name hides the page field.
+var pageNameFields = []string{"ID", "CreatedTime", "LastEditedTime"}
+
+// templateDate is a date in a page name template, written with the default date format.
+type templateDate struct {
+	time   time.Time
+	format string
+}
+
+func (d templateDate) String() string {
+	return timefmt.Format(d.time, d.format)
+}
+
+// parsePageNameTemplate parses the page name template of the configuration, nil when there is none.
+// Missing keys are errors, pages of other databases without the properties of the template keep their title.
+func (m *migrator) parsePageNameTemplate() (*template.Template, error) {
+	if m.config.PageNameTemplate == "" {
+		return nil, nil
+	}
+
+	pageName, err := template.New("page-name").
+		Option("missingkey=error").
+		Funcs(m.pageNameFuncs()).
+		Parse(m.config.PageNameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the page name template %s. error: %w", m.config.PageNameTemplate, err)
+	}
+
+	return pageName, nil
+}
+
+func (m *migrator) pageNameFuncs() template.FuncMap {
+	return template.FuncMap{
+		"date": func(value any, format string) (string, error) {
+			switch v := value.(type) {
+			case templateDate:
+				return timefmt.Format(v.time, format), nil
+			case string:
+				// Empty dates are empty strings
+				return v, nil
+			default:
+				return "", fmt.Errorf("date expects a date property, got %T", value)
+			}
+		},
+		"slug": func(value any) string {
+			return slugify(fmt.Sprint(value))
+		},
+		"lower": func(value any) string {
+			return strings.ToLower(fmt.Sprint(value))
+		},
+		"upper": func(value any) string {
+			return strings.ToUpper(fmt.Sprint(value))
+		},
+		"truncate": func(length int, value any) string {
+			text := []rune(fmt.Sprint(value))
+			if length < 0 || len(text) <= length {
+				return string(text)
+			}
+			return strings.TrimSpace(string(text[:length]))
+		},
+		"join": func(separator string, values []string) string {
+			return strings.Join(values, separator)
+		},
+	}
+}
+
+// validatePageNameTemplate checks the properties used by the page name template exist in the database.
+func (m *migrator) validatePageNameTemplate(properties notion.DatabaseProperties) error {
+	if m.pageNameTemplate == nil {
+		return nil
+	}
+
+	known := map[string]bool{}
+	for _, field := range pageNameFields {
+		known[field] = true
+	}
+	for name := range properties {
+		known[name] = true
+	}
+
+	unknown := map[string]bool{}
+	for _, name := range templateFields(m.pageNameTemplate.Tree.Root) {
+		if !known[name] {
+			unknown[name] = true
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(unknown))
+	for name := range unknown {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	available := make([]string, 0, len(known))
+	for name := range known {
+		available = append(available, name)
+	}
+	sort.Strings(available)
+
+	return fmt.Errorf(
+		"the page name template uses unknown properties %s. available properties: %s",
+		strings.Join(names, ", "),
+		strings.Join(available, ", "),
+	)
+}
+
+// templateFields returns the page properties used by a template: `.Name` and `index . "Name"`.
+// The fields inside range and with are relative to another value, they are not checked.
+func templateFields(node parse.Node) []string {
+	fields := []string{}
+
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return fields
+		}
+		for _, child := range n.Nodes {
+			fields = append(fields, templateFields(child)...)
+		}
+	case *parse.ActionNode:
+		fields = append(fields, templateFields(n.Pipe)...)
+	case *parse.IfNode:
+		fields = append(fields, templateFields(n.Pipe)...)
+		fields = append(fields, templateFields(n.List)...)
+		fields = append(fields, templateFields(n.ElseList)...)
+	case *parse.RangeNode:
+		fields = append(fields, templateFields(n.Pipe)...)
+		fields = append(fields, templateFields(n.ElseList)...)
+	case *parse.WithNode:
+		fields = append(fields, templateFields(n.Pipe)...)
+		fields = append(fields, templateFields(n.ElseList)...)
+	case *parse.PipeNode:
+		if n == nil {
+			return fields
+		}
+		for _, command := range n.Cmds {
+			fields = append(fields, templateFields(command)...)
+		}
+	case *parse.CommandNode:
+		if len(n.Args) == 3 {
+			identifier, isIdentifier := n.Args[0].(*parse.IdentifierNode)
+			_, isDot := n.Args[1].(*parse.DotNode)
+			name, isString := n.Args[2].(*parse.StringNode)
+			if isIdentifier && identifier.Ident == "index" && isDot && isString {
+				fields = append(fields, name.Text)
+			}
+		}
+		for _, arg := range n.Args {
+			fields = append(fields, templateFields(arg)...)
+		}
+	case *parse.FieldNode:
+		fields = append(fields, n.Ident[0])
+	}
+
+	return fields
+}
+
+// templatePageName renders the page name template for a page.
+func (m *migrator) templatePageName(page notion.Page) (string, error) {
+	buffer := &strings.Builder{}
+	if err := m.pageNameTemplate.Execute(buffer, m.pageNameData(page)); err != nil {
+		return "", err
+	}
+
+	name := strings.TrimSpace(buffer.String())
+	if name == "" {
+		return "", errors.New("the page name template returned an empty name")
+	}
+
+	return name, nil
+}
+
+// pageNameData returns the values available in the page name template, keyed by page property name.
+// The text values are sanitized, only the slashes written in the template create folders.
+func (m *migrator) pageNameData(page notion.Page) map[string]any {
+	data := map[string]any{
+		"ID":             page.ID,
+		"CreatedTime":    templateDate{time: m.localTime(notion.NewDateTime(page.CreatedTime, true)), format: m.dateFormat()},
+		"LastEditedTime": templateDate{time: m.localTime(notion.NewDateTime(page.LastEditedTime, true)), format: m.dateFormat()},
+	}
+
+	switch properties := page.Properties.(type) {
+	case notion.DatabasePageProperties:
+		for name, property := range properties {
+			data[name] = m.templateValue(property)
+		}
+	case notion.PageProperties:
+		data["Title"] = extractPlainTextFromRichText(properties.Title.Title)
+	}
+
+	for name, value := range data {
+		data[name] = m.sanitizeTemplateValue(value)
+	}
+
+	return data
+}
+
+// sanitizeTemplateValue sanitizes the text values and the lists of text values of the page name template.
+func (m *migrator) sanitizeTemplateValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return m.sanitizeName(v)
+	case []string:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, m.sanitizeName(item))
+		}
+		return values
+	default:
+		return value
+	}
+}
+
+// templateValue returns the value of a page property in the page name template.
+// Empty properties are empty strings, lists are lists of strings and dates are written with the default format.
+func (m *migrator) templateValue(property notion.DatabasePageProperty) any {
+	switch property.Type {
+	case notion.DBPropTypeTitle:
+		return extractPlainTextFromRichText(property.Title)
+	case notion.DBPropTypeRichText:
+		return extractPlainTextFromRichText(property.RichText)
+	case notion.DBPropTypeNumber:
+		if property.Number != nil {
+			return *property.Number
+		}
+	case notion.DBPropTypeSelect:
+		if property.Select != nil {
+			return property.Select.Name
+		}
+	case notion.DBPropTypeStatus:
+		if property.Status != nil {
+			return property.Status.Name
+		}
+	case notion.DBPropTypeMultiSelect:
+		options := []string{}
+		for _, option := range property.MultiSelect {
+			options = append(options, option.Name)
+		}
+		return options
+	case notion.DBPropTypeDate:
+		if property.Date != nil {
+			return m.templateDate(m.inTimeZone(*property.Date).Start)
+		}
+	case notion.DBPropTypePeople:
+		people := []string{}
+		for _, user := range property.People {
+			people = append(people, userName(user))
+		}
+		return people
+	case notion.DBPropTypeFiles:
+		files := []string{}
+		for _, file := range property.Files {
+			files = append(files, file.Name)
+		}
+		return files
+	case notion.DBPropTypeCheckbox:
+		if property.Checkbox != nil {
+			return *property.Checkbox
+		}
+	case notion.DBPropTypeURL:
+		if property.URL != nil {
+			return *property.URL
+		}
+	case notion.DBPropTypeEmail:
+		if property.Email != nil {
+			return *property.Email
+		}
+	case notion.DBPropTypePhoneNumber:
+		if property.PhoneNumber != nil {
+			return *property.PhoneNumber
+		}
+	case notion.DBPropTypeFormula:
+		if property.Formula != nil {
+			return m.formulaTemplateValue(*property.Formula)
+		}
+	case notion.DBPropTypeRelation:
+		// The related pages are not fetched to build the page names, their IDs are used.
+		ids := []string{}
+		for _, relation := range property.Relation {
+			ids = append(ids, relation.ID)
+		}
+		return ids
+	case notion.DBPropTypeRollup:
+		if property.Rollup != nil {
+			return m.rollupTemplateValue(*property.Rollup)
+		}
+	case notion.DBPropTypeCreatedTime:
+		if property.CreatedTime != nil {
+			return m.templateDate(notion.NewDateTime(*property.CreatedTime, true))
+		}
+	case notion.DBPropTypeLastEditedTime:
+		if property.LastEditedTime != nil {
+			return m.templateDate(notion.NewDateTime(*property.LastEditedTime, true))
+		}
+	case notion.DBPropTypeCreatedBy:
+		if property.CreatedBy != nil {
+			return userName(*property.CreatedBy)
+		}
+	case notion.DBPropTypeLastEditedBy:
+		if property.LastEditedBy != nil {
+			return userName(*property.LastEditedBy)
+		}
+	}
+
+	return ""
+}
+
+func (m *migrator) formulaTemplateValue(formula notion.FormulaResult) any {
+	switch {
+	case formula.String != nil:
+		return *formula.String
+	case formula.Number != nil:
+		return *formula.Number
+	case formula.Boolean != nil:
+		return *formula.Boolean
+	case formula.Date != nil:
+		return m.templateDate(m.inTimeZone(*formula.Date).Start)
+	default:
+		return ""
+	}
+}
+
+func (m *migrator) rollupTemplateValue(rollup notion.RollupResult) any {
+	switch {
+	case rollup.Number != nil:
+		return *rollup.Number
+	case rollup.Date != nil:
+		return m.templateDate(m.inTimeZone(*rollup.Date).Start)
+	case rollup.Type == notion.RollupResultTypeArray:
+		values := []string{}
+		for _, element := range rollup.Array {
+			for _, value := range listValues(m.templateValue(element)) {
+				values = append(values, fmt.Sprint(value))
+			}
+		}
+		return values
+	default:
+		return ""
+	}
+}
+
+func (m *migrator) templateDate(date notion.DateTime) templateDate {
+	return templateDate{time: m.localTime(date), format: m.dateFormat()}
+}
+
+// userName returns the name of a user included in a page, without fetching the users without name.
+func userName(user notion.User) string {
+	if user.Name != "" {
+		return user.Name
+	}
+
+	if user.Person != nil && user.Person.Email != "" {
+		return user.Person.Email
+	}
+
+	return user.ID
+}
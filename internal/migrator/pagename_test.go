@@ -0,0 +1,122 @@
+package migrator
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/dstotijn/go-notion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePageNameTemplate(t *testing.T) {
+	properties := notion.DatabaseProperties{
+		"Name":   notion.DatabaseProperty{Type: notion.DBPropTypeTitle},
+		"Status": notion.DatabaseProperty{Type: notion.DBPropTypeStatus},
+		"Due":    notion.DatabaseProperty{Type: notion.DBPropTypeDate},
+		"Tags":   notion.DatabaseProperty{Type: notion.DBPropTypeMultiSelect},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		err      string
+	}{
+		{
+			name:     "known properties",
+			template: `{{.Status}}/{{date .Due "%Y-%m"}} - {{.Name | truncate 40}}`,
+		},
+		{
+			name:     "page fields",
+			template: `{{date .CreatedTime "%Y"}} {{.ID}} {{.LastEditedTime}}`,
+		},
+		{
+			name:     "properties inside range and with",
+			template: `{{range .Tags}}{{.}}{{end}}{{with .Status}}{{.}}{{else}}{{.Name}}{{end}}`,
+		},
+		{
+			name:     "unknown property",
+			template: `{{.Stage}} - {{.Name}}`,
+			err:      "the page name template uses unknown properties Stage",
+		},
+		{
+			name:     "unknown property inside if",
+			template: `{{if .Due}}{{.Deadline}}{{else}}{{.Owner}}{{end}}`,
+			err:      "unknown properties Deadline, Owner",
+		},
+		{
+			name:     "unknown property with index",
+			template: `{{index . "Due date"}}`,
+			err:      "unknown properties Due date. available properties: CreatedTime, Due, ID",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := &migrator{config: &config.Config{PageNameTemplate: test.template}}
+
+			var err error
+			m.pageNameTemplate, err = m.parsePageNameTemplate()
+			require.NoError(t, err)
+
+			err = m.validatePageNameTemplate(properties)
+			if test.err == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestParsePageNameTemplate_Invalid(t *testing.T) {
+	m := &migrator{config: &config.Config{PageNameTemplate: `{{.Name | unknown}}`}}
+
+	_, err := m.parsePageNameTemplate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse the page name template")
+}
+
+func TestPageNameFuncs(t *testing.T) {
+	m := &migrator{config: &config.Config{}}
+
+	names := []string{}
+	for name := range m.pageNameFuncs() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// The config file validation parses the templates with the same functions.
+	assert.Equal(t, config.PageNameTemplateFuncs, names)
+}
+
+func TestTemplatePageName_SanitizesValues(t *testing.T) {
+	m := &migrator{config: &config.Config{PageNameTemplate: `Notes/{{.Name}}{{range .Tags}} {{.}}{{end}}`}}
+
+	var err error
+	m.pageNameTemplate, err = m.parsePageNameTemplate()
+	require.NoError(t, err)
+
+	page := notion.Page{
+		ID: "b0000000-0000-0000-0000-000000000001",
+		Properties: notion.DatabasePageProperties{
+			"Name": notion.DatabasePageProperty{
+				Type:  notion.DBPropTypeTitle,
+				Title: []notion.RichText{{PlainText: "Client/Server: A?"}},
+			},
+			"Tags": notion.DatabasePageProperty{
+				Type:        notion.DBPropTypeMultiSelect,
+				MultiSelect: []notion.SelectOptions{{Name: "draft/v2"}},
+			},
+		},
+	}
+
+	name, err := m.templatePageName(page)
+	require.NoError(t, err)
+
+	// The slashes of the values are replaced, the slash of the template creates a folder.
+	assert.Equal(t, "Notes/Client-Server- A draft-v2.md", m.sanitizePageName(name))
+}
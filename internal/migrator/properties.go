@@ -0,0 +1,317 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/GustavoCaso/n2o/internal/frontmatter"
+	"github.com/dstotijn/go-notion"
+)
+
+// migrateProperty reports if a Notion property is written in the frontmatter.
+// Mapped properties are migrated even when they are not listed in the page properties, unless they are dropped.
+func (m *migrator) migrateProperty(name string, pageProperties map[string]bool) bool {
+	mapping, mapped := m.config.PropertyMappings[strings.ToLower(name)]
+	if mapped {
+		return mapping.Action != config.PropertyDrop
+	}
+
+	return pageProperties["all"] || pageProperties[strings.ToLower(name)]
+}
+
+// frontmatterProperties collects the frontmatter properties, applying the property mappings.
+// Properties written to the same key are merged in a list, in the order they are added.
+type frontmatterProperties struct {
+	mappings map[string]config.PropertyMapping
+	keys     []string
+	values   map[string]any
+}
+
+func newFrontmatterProperties(mappings map[string]config.PropertyMapping) *frontmatterProperties {
+	return &frontmatterProperties{mappings: mappings, values: map[string]any{}}
+}
+
+func (p *frontmatterProperties) add(name string, value any) {
+	key := name
+
+	if mapping, ok := p.mappings[strings.ToLower(name)]; ok {
+		switch mapping.Action {
+		case config.PropertyDrop:
+			return
+		case config.PropertyTags:
+			key = mapping.Key
+			value = tags(value, mapping.TagPrefix)
+		case config.PropertyAliases:
+			key = mapping.Key
+			value = listValues(value)
+		case config.PropertyRename:
+			key = mapping.Key
+		}
+	}
+
+	p.put(key, value)
+}
+
+// addRange adds the start and end of a range as `<key>_start` and `<key>_end`, using the key of renamed properties.
+// The end is only added when there is one. Ranges added to the tags or aliases only add their start.
+func (p *frontmatterProperties) addRange(name string, start, end any) {
+	key := name
+
+	if mapping, ok := p.mappings[strings.ToLower(name)]; ok {
+		switch mapping.Action {
+		case config.PropertyDrop:
+			return
+		case config.PropertyRename:
+			key = mapping.Key
+		default:
+			p.add(name, start)
+			return
+		}
+	}
+
+	p.put(key+"_start", start)
+	if end != nil {
+		p.put(key+"_end", end)
+	}
+}
+
+func (p *frontmatterProperties) put(key string, value any) {
+	previous, ok := p.values[key]
+	if !ok {
+		p.keys = append(p.keys, key)
+		p.values[key] = value
+		return
+	}
+
+	merged := listValues(previous)
+	for _, item := range listValues(value) {
+		if !contains(merged, item) {
+			merged = append(merged, item)
+		}
+	}
+	p.values[key] = merged
+}
+
+func (p *frontmatterProperties) frontmatter() (*frontmatter.Frontmatter, error) {
+	properties := frontmatter.New()
+
+	for _, key := range p.keys {
+		if err := properties.Set(key, p.values[key]); err != nil {
+			return nil, err
+		}
+	}
+
+	return properties, nil
+}
+
+// listValues returns the value as a list, empty values are skipped.
+func listValues(value any) []any {
+	switch v := value.(type) {
+	case nil:
+		return []any{}
+	case []any:
+		return v
+	case []string:
+		items := make([]any, 0, len(v))
+		for _, item := range v {
+			items = append(items, item)
+		}
+		return items
+	case string:
+		if v == "" {
+			return []any{}
+		}
+		return []any{v}
+	default:
+		return []any{v}
+	}
+}
+
+func contains(items []any, value any) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// tags turns the values of a property into Obsidian tags, which can not contain spaces nor punctuation.
+func tags(value any, prefix string) []any {
+	prefix = strings.TrimPrefix(prefix, "#")
+
+	result := []any{}
+	for _, item := range listValues(value) {
+		tag := slugify(fmt.Sprint(item))
+		if tag == "" {
+			continue
+		}
+
+		tag = prefix + tag
+		if !contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// slugify lowercases the text and replaces the characters not allowed in Obsidian tags with dashes.
+// Slashes are kept, they nest tags.
+func slugify(text string) string {
+	slug := []rune{}
+
+	for _, r := range strings.ToLower(text) {
+		last := len(slug) - 1
+
+		switch {
+		case r == '/':
+			if last >= 0 && slug[last] == '-' {
+				slug = slug[:last]
+			}
+			slug = append(slug, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || r == '_':
+			slug = append(slug, r)
+		case last >= 0 && slug[last] != '-' && slug[last] != '/':
+			slug = append(slug, '-')
+		}
+	}
+
+	return strings.Trim(string(slug), "-/")
+}
+
+const (
+	// propertyReferenceLimit is the number of relations, people and rollup values included in the page properties.
+	propertyReferenceLimit = 25
+	// propertyRichTextLimit is the number of rich text items included in the title and rich_text page properties.
+	propertyRichTextLimit = 100
+)
+
+// truncated reports if Notion may have left out values of a page property.
+// go-notion does not keep the has_more of the page properties, the properties with as many values as
+// the limits are fetched again. Rollups are computed from the first relations only,
+// they are fetched again when a relation of the page is truncated.
+func truncated(property notion.DatabasePageProperty, relationsTruncated bool) bool {
+	if property.ID == "" {
+		return false
+	}
+
+	switch property.Type {
+	case notion.DBPropTypeRelation:
+		return len(property.Relation) >= propertyReferenceLimit
+	case notion.DBPropTypePeople:
+		return len(property.People) >= propertyReferenceLimit
+	case notion.DBPropTypeTitle:
+		return len(property.Title) >= propertyRichTextLimit
+	case notion.DBPropTypeRichText:
+		return len(property.RichText) >= propertyRichTextLimit
+	case notion.DBPropTypeRollup:
+		if property.Rollup == nil {
+			return false
+		}
+		return relationsTruncated || len(property.Rollup.Array) >= propertyReferenceLimit
+	default:
+		return false
+	}
+}
+
+// completeProperty fetches every value of a page property from the page property endpoint.
+// https://developers.notion.com/reference/retrieve-a-page-property
+func (m *migrator) completeProperty(
+	ctx context.Context,
+	pageID string,
+	property notion.DatabasePageProperty,
+) (notion.DatabasePageProperty, error) {
+	result := notion.DatabasePageProperty{ID: property.ID, Type: property.Type, Name: property.Name}
+	rollupValues := []notion.DatabasePageProperty{}
+
+	query := &notion.PaginationQuery{}
+	for {
+		response, err := m.notionClient.FindPagePropertyByID(ctx, pageID, property.ID, query)
+		if err != nil {
+			return property, fmt.Errorf("failed to fetch the page property %s. error: %w", property.Name, err)
+		}
+
+		for _, item := range response.Results {
+			switch property.Type {
+			case notion.DBPropTypeRelation:
+				result.Relation = append(result.Relation, item.Relation)
+			case notion.DBPropTypePeople:
+				result.People = append(result.People, item.People)
+			case notion.DBPropTypeTitle:
+				result.Title = append(result.Title, item.Title)
+			case notion.DBPropTypeRichText:
+				result.RichText = append(result.RichText, item.RichText)
+			case notion.DBPropTypeRollup:
+				rollupValues = append(rollupValues, propertyItemValue(item))
+			}
+		}
+
+		if property.Type == notion.DBPropTypeRollup {
+			// The rollup value is computed from the pages of the relation requested so far.
+			rollup := response.PropertyItem.Rollup
+			result.Rollup = &rollup
+		}
+
+		if !response.HasMore || response.NextCursor == "" {
+			break
+		}
+		query.StartCursor = response.NextCursor
+	}
+
+	if result.Rollup != nil && result.Rollup.Type == notion.RollupResultTypeArray {
+		result.Rollup.Array = rollupValues
+	}
+
+	return result, nil
+}
+
+// propertyItemValue returns a property item of the page property endpoint as a page property.
+func propertyItemValue(item notion.PagePropItem) notion.DatabasePageProperty {
+	property := notion.DatabasePageProperty{Type: item.Type}
+
+	switch item.Type {
+	case notion.DBPropTypeTitle:
+		property.Title = []notion.RichText{item.Title}
+	case notion.DBPropTypeRichText:
+		property.RichText = []notion.RichText{item.RichText}
+	case notion.DBPropTypeNumber:
+		property.Number = &item.Number
+	case notion.DBPropTypeSelect:
+		property.Select = &item.Select
+	case notion.DBPropTypeMultiSelect:
+		property.MultiSelect = []notion.SelectOptions{item.MultiSelect}
+	case notion.DBPropTypeDate:
+		property.Date = &item.Date
+	case notion.DBPropTypeFormula:
+		property.Formula = &item.Formula
+	case notion.DBPropTypeRelation:
+		property.Relation = []notion.Relation{item.Relation}
+	case notion.DBPropTypePeople:
+		property.People = []notion.User{item.People}
+	case notion.DBPropTypeFiles:
+		property.Files = []notion.File{item.Files}
+	case notion.DBPropTypeCheckbox:
+		property.Checkbox = &item.Checkbox
+	case notion.DBPropTypeURL:
+		property.URL = &item.URL
+	case notion.DBPropTypeEmail:
+		property.Email = &item.Email
+	case notion.DBPropTypePhoneNumber:
+		property.PhoneNumber = &item.PhoneNumber
+	case notion.DBPropTypeCreatedTime:
+		property.CreatedTime = &item.CreatedTime
+	case notion.DBPropTypeCreatedBy:
+		property.CreatedBy = &item.CreatedBy
+	case notion.DBPropTypeLastEditedTime:
+		property.LastEditedTime = &item.LastEditedTime
+	case notion.DBPropTypeLastEditedBy:
+		property.LastEditedBy = &item.LastEditedBy
+	}
+
+	return property
+}
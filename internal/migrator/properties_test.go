@@ -0,0 +1,65 @@
+package migrator
+
+import (
+	"testing"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Deep Work":           "deep-work",
+		"  Q&A  ":             "q-a",
+		"Projects / Active":   "projects/active",
+		"already-a-slug":      "already-a-slug",
+		"snake_case":          "snake_case",
+		"Café con leche":      "café-con-leche",
+		"#hashtag!":           "hashtag",
+		"2024 Goals":          "2024-goals",
+		"/leading/trailing/":  "leading/trailing",
+		"!!!":                 "",
+		"Multiple   spaces--": "multiple-spaces",
+	}
+
+	for text, expected := range tests {
+		assert.Equal(t, expected, slugify(text), text)
+	}
+}
+
+func TestFrontmatterProperties(t *testing.T) {
+	properties := newFrontmatterProperties(map[string]config.PropertyMapping{
+		"area":     {Action: config.PropertyTags, Key: "tags", TagPrefix: "#area/"},
+		"labels":   {Action: config.PropertyTags, Key: "tags"},
+		"nickname": {Action: config.PropertyAliases, Key: "aliases"},
+		"type":     {Action: config.PropertyRename, Key: "kind"},
+		"category": {Action: config.PropertyRename, Key: "kind"},
+		"internal": {Action: config.PropertyDrop},
+	})
+
+	properties.add("Title", "Focus")
+	properties.add("Area", "Deep Work")
+	properties.add("Labels", []string{"Reading List", "reading list", ""})
+	properties.add("Nickname", "")
+	properties.add("Type", "Note")
+	properties.add("Category", []string{"Idea", "Note"})
+	properties.add("Internal", "secret")
+	properties.add("Score", 3.0)
+
+	result, err := properties.frontmatter()
+	require.NoError(t, err)
+
+	assert.Equal(t, `---
+Title: Focus
+tags:
+  - area/deep-work
+  - reading-list
+aliases: []
+kind:
+  - Note
+  - Idea
+Score: 3
+---
+`, result.String())
+}
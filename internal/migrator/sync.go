@@ -0,0 +1,273 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/GustavoCaso/n2o/internal/state"
+)
+
+// syncing reports if only the pages edited since the last run have to be migrated.
+func (m *migrator) syncing() bool {
+	return m.config.Sync && m.state != nil
+}
+
+// changedPages returns the pages edited since the last run. The other pages are marked as unchanged,
+// they stay in the cache so links to them are kept but their content is not fetched again.
+func (m *migrator) changedPages(ctx context.Context, pages []*Page) []*Page {
+	changed := []*Page{}
+
+	for _, page := range pages {
+		if m.isUnchanged(ctx, page) {
+			page.unchanged = true
+			continue
+		}
+
+		changed = append(changed, page)
+	}
+
+	return changed
+}
+
+// isUnchanged reports if the page was migrated to the same path and has not been edited since.
+// Editing a child page does not change the last_edited_time of its parent, so when migrating
+// the child pages we also check the child pages recorded in the state.
+func (m *migrator) isUnchanged(ctx context.Context, page *Page) bool {
+	entry, ok := m.state.Get(page.id)
+	if !ok || entry.Path != m.removeObsidianVault(page.Path) {
+		return false
+	}
+
+	if page.notionPage.LastEditedTime.After(entry.LastEditedTime) {
+		return false
+	}
+
+	if _, err := os.Stat(page.Path); err != nil {
+		return false
+	}
+
+	if m.config.Recursive && !m.config.Workspace {
+		return m.childrenUnchanged(ctx, page.id, map[string]bool{page.id: true})
+	}
+
+	return true
+}
+
+// childrenUnchanged checks the child pages recorded in the state of pageID.
+// Removed or archived child pages count as changes, so the parent page is migrated again.
+func (m *migrator) childrenUnchanged(ctx context.Context, pageID string, visited map[string]bool) bool {
+	for _, childID := range m.state.Children(pageID) {
+		if visited[childID] {
+			continue
+		}
+		visited[childID] = true
+
+		entry, _ := m.state.Get(childID)
+
+		notionPage, err := m.notionClient.FindPageByID(ctx, childID)
+		if err != nil {
+			m.debugLog(fmt.Sprintf("failed to check child page %s, migrating its parent. error: %v\n", childID, err))
+			return false
+		}
+
+		if notionPage.Archived || notionPage.LastEditedTime.After(entry.LastEditedTime) {
+			return false
+		}
+
+		if !m.childrenUnchanged(ctx, childID, visited) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// fetchPageContent fetches the content of a child or mentioned page,
+// unless the page did not change since the last sync.
+func (m *migrator) fetchPageContent(ctx context.Context, page *Page) error {
+	if m.syncing() && m.isUnchanged(ctx, page) {
+		page.unchanged = true
+		return nil
+	}
+
+	return m.FetchParseAndSavePage(ctx, page, m.config.PagePropertiesToMigrate)
+}
+
+// recordPage saves the page in the state and reports if its content has to be written.
+// When the page path changed since the last run, the previous note is moved to the new path.
+func (m *migrator) recordPage(page *Page, content string) (bool, error) {
+	if m.state == nil {
+		return true, nil
+	}
+
+	notePath := m.removeObsidianVault(page.Path)
+	hash := state.Hash(content)
+	write := true
+	parent := m.parentKey(page)
+	// The pages referencing the page in the last run keep referencing it until they are migrated again.
+	references := []string{}
+
+	if entry, ok := m.state.Get(page.id); ok {
+		for _, reference := range append([]string{entry.Parent}, entry.References...) {
+			if reference != "" && reference != parent {
+				references = append(references, reference)
+			}
+		}
+
+		if entry.Path != notePath {
+			if err := m.moveNote(entry.Path, notePath); err != nil {
+				return false, err
+			}
+		}
+
+		if _, err := os.Stat(page.Path); err == nil && entry.Hash == hash {
+			write = false
+		}
+	}
+
+	m.state.Set(page.id, state.Entry{
+		LastEditedTime: page.notionPage.LastEditedTime,
+		Path:           notePath,
+		Hash:           hash,
+		Parent:         parent,
+	})
+	for _, reference := range references {
+		m.state.Reference(page.id, reference)
+	}
+
+	return write, nil
+}
+
+// removeDeletedPages handles the notes of the pages removed from Notion since the last sync.
+// A page is removed when it is no longer listed in the source, and no longer referenced by any page.
+// The child pages of a removed page are removed as well. A filtered source only lists the pages matching
+// the filter, the notes of its other pages are kept.
+func (m *migrator) removeDeletedPages(written map[string]*Page) error {
+	if !m.syncing() {
+		return nil
+	}
+
+	live := map[string]bool{}
+	for _, page := range m.listed {
+		live[page.id] = true
+	}
+
+	// Only the pages migrated again know all their children, the children of unchanged and failed pages are kept.
+	parents := []string{}
+	if m.filter == nil {
+		parents = append(parents, m.sourceKey())
+	}
+	for _, page := range written {
+		live[page.id] = true
+		if !page.unchanged && !m.hasFailed(page.id) {
+			parents = append(parents, page.id)
+			for _, child := range page.children {
+				if child != page {
+					m.state.Reference(child.id, page.id)
+				}
+			}
+		}
+	}
+
+	// The search returns every page of the workspace, pages not listed have been removed.
+	if m.config.Workspace {
+		for _, page := range m.listed {
+			parents = append(parents, page.id)
+		}
+	}
+
+	for len(parents) > 0 {
+		parent := parents[0]
+		parents = parents[1:]
+
+		for _, id := range m.state.Children(parent) {
+			if live[id] {
+				continue
+			}
+
+			// Unchanged pages referencing the page still link to it, its note is kept.
+			if m.state.Unreference(id, parent) {
+				continue
+			}
+
+			if err := m.removePage(id); err != nil {
+				return err
+			}
+
+			parents = append(parents, id)
+		}
+	}
+
+	return nil
+}
+
+func (m *migrator) removePage(id string) error {
+	entry, _ := m.state.Get(id)
+	notePath := filepath.Join(m.config.VaultPath, entry.Path)
+
+	switch m.config.SyncRemoved {
+	case config.SyncRemovedDelete:
+		m.logger.Info(fmt.Sprintf("deleting %s, the page was removed from Notion\n", entry.Path))
+		if err := os.Remove(notePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to delete the note %s. error: %w", entry.Path, err)
+		}
+	case config.SyncRemovedArchive:
+		archivePath := filepath.Join(state.Dir, "archive", entry.Path)
+		m.logger.Info(fmt.Sprintf("archiving %s to %s, the page was removed from Notion\n", entry.Path, archivePath))
+		if err := m.moveNote(entry.Path, archivePath); err != nil {
+			return err
+		}
+	default:
+		m.logger.Info(fmt.Sprintf("keeping %s, the page was removed from Notion\n", entry.Path))
+	}
+
+	m.state.Delete(id)
+
+	return nil
+}
+
+// moveNote moves a note inside the Obsidian vault. Missing notes are ignored.
+func (m *migrator) moveNote(from, to string) error {
+	fromPath := filepath.Join(m.config.VaultPath, from)
+	toPath := filepath.Join(m.config.VaultPath, to)
+
+	if _, err := os.Stat(fromPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	m.debugLog(fmt.Sprintf("moving %s to %s\n", from, to))
+
+	if err := os.MkdirAll(filepath.Dir(toPath), 0750); err != nil {
+		return fmt.Errorf("failed to create the necessary directories in for the Obsidian vault.  error: %w", err)
+	}
+
+	if err := os.Rename(fromPath, toPath); err != nil {
+		return fmt.Errorf("failed to move the note %s to %s. error: %w", from, to, err)
+	}
+
+	return nil
+}
+
+// sourceKey identifies the source in the state, it is the parent of the pages listed by the source.
+func (m *migrator) sourceKey() string {
+	switch {
+	case m.config.Workspace:
+		return "workspace"
+	case m.config.DatabaseID != "":
+		return "database:" + m.config.DatabaseID
+	default:
+		return "page:" + m.config.PageID
+	}
+}
+
+func (m *migrator) parentKey(page *Page) string {
+	if page.parent != nil {
+		return page.parent.id
+	}
+
+	return m.sourceKey()
+}
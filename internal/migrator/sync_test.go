@@ -0,0 +1,393 @@
+package migrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/GustavoCaso/n2o/internal/dbquery"
+	"github.com/GustavoCaso/n2o/internal/log"
+	"github.com/GustavoCaso/n2o/internal/notiontest"
+	"github.com/GustavoCaso/n2o/internal/state"
+	"github.com/dstotijn/go-notion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSync(t *testing.T) {
+	lastRun := time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC)
+	pageContent := `## Lacinato kale
+[Lacinato kale is a variety of kale with a long tradition in Italian cuisine, especially that of Tuscany. It is also known as Tuscan kale, Italian kale, dinosaur kale, kale, flat back kale, palm tree kale, or black Tuscan palm.](https://en.wikipedia.org/wiki/Lacinato_kale)
+`
+
+	tests := []struct {
+		name        string
+		syncRemoved string
+		assertions  func(t *testing.T, vault string)
+	}{
+		{
+			name:        "keeps the notes of removed pages",
+			syncRemoved: config.SyncRemovedKeep,
+			assertions: func(t *testing.T, vault string) {
+				assert.FileExists(t, filepath.Join(vault, "Tasks/Removed.md"))
+			},
+		},
+		{
+			name:        "deletes the notes of removed pages",
+			syncRemoved: config.SyncRemovedDelete,
+			assertions: func(t *testing.T, vault string) {
+				assertNoFile(t, filepath.Join(vault, "Tasks/Removed.md"))
+				assertNoFile(t, filepath.Join(vault, "Tasks/Removed/Subtask.md"))
+			},
+		},
+		{
+			name:        "archives the notes of removed pages",
+			syncRemoved: config.SyncRemovedArchive,
+			assertions: func(t *testing.T, vault string) {
+				assertNoFile(t, filepath.Join(vault, "Tasks/Removed.md"))
+				assert.FileExists(t, filepath.Join(vault, ".n2o/archive/Tasks/Removed.md"))
+				assert.FileExists(t, filepath.Join(vault, ".n2o/archive/Tasks/Removed/Subtask.md"))
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			vault := t.TempDir()
+
+			for notePath, content := range map[string]string{
+				"Tasks/Unchanged.md":       "unchanged content",
+				"Tasks/Old name.md":        "previous content",
+				"Tasks/Same content.md":    "content written by the user",
+				"Tasks/Removed.md":         "removed content",
+				"Tasks/Removed/Subtask.md": "removed subtask content",
+			} {
+				require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(vault, notePath)), 0750))
+				require.NoError(t, os.WriteFile(filepath.Join(vault, notePath), []byte(content), 0600))
+			}
+
+			vaultState, err := state.Load(vault)
+			require.NoError(t, err)
+
+			for id, entry := range map[string]state.Entry{
+				"unchanged":    {Path: "Tasks/Unchanged.md", Hash: state.Hash("unchanged content")},
+				"renamed":      {Path: "Tasks/Old name.md", Hash: state.Hash("previous content")},
+				"same-content": {Path: "Tasks/Same content.md", Hash: state.Hash(pageContent)},
+				"removed":      {Path: "Tasks/Removed.md", Hash: state.Hash("removed content")},
+			} {
+				entry.LastEditedTime = lastRun
+				entry.Parent = "database:000000"
+				vaultState.Set(id, entry)
+			}
+			vaultState.Set("subtask", state.Entry{Path: "Tasks/Removed/Subtask.md", Parent: "removed"})
+
+			newPage := func(id, notePath string, lastEditedTime time.Time) *Page {
+				return &Page{
+					id:         id,
+					buffer:     &strings.Builder{},
+					Path:       filepath.Join(vault, notePath),
+					notionPage: notion.Page{ID: id, LastEditedTime: lastEditedTime},
+				}
+			}
+
+			listed := []*Page{
+				newPage("unchanged", "Tasks/Unchanged.md", lastRun),
+				newPage("renamed", "Tasks/New name.md", lastRun.Add(time.Hour)),
+				newPage("same-content", "Tasks/Same content.md", lastRun.Add(time.Hour)),
+				newPage("new", "Tasks/New.md", lastRun.Add(time.Hour)),
+			}
+
+			httpClient := &http.Client{
+				Transport: &mockRoundtripper{fn: func(_ *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: 200,
+						Body:       io.NopCloser(bytes.NewReader(mustReadFixture("fixtures/page_blocks.json"))),
+					}, nil
+				}},
+			}
+
+			logger, _ := log.MockLogger()
+
+			m := migrator{
+				notionClient: notion.NewClient("secret-api-key", notion.WithHTTPClient(httpClient)),
+				config: &config.Config{
+					DatabaseID:  "000000",
+					VaultPath:   vault,
+					Sync:        true,
+					SyncRemoved: test.syncRemoved,
+				},
+				cache:  NewCache(),
+				logger: logger,
+				state:  vaultState,
+				listed: listed,
+			}
+
+			ctx := context.TODO()
+
+			m.pages = m.changedPages(ctx, listed)
+
+			ids := []string{}
+			for _, page := range m.pages {
+				ids = append(ids, page.id)
+			}
+			assert.Equal(t, []string{"renamed", "same-content", "new"}, ids)
+
+			for _, page := range m.pages {
+				require.NoError(t, m.FetchParseAndSavePage(ctx, page, map[string]bool{}))
+			}
+
+			require.NoError(t, m.WritePagesToDisk(ctx))
+
+			assertFileContent(t, filepath.Join(vault, "Tasks/Unchanged.md"), "unchanged content")
+			assertFileContent(t, filepath.Join(vault, "Tasks/New name.md"), pageContent)
+			assertFileContent(t, filepath.Join(vault, "Tasks/New.md"), pageContent)
+			// The content did not change since the last run, the note is not written again.
+			assertFileContent(t, filepath.Join(vault, "Tasks/Same content.md"), "content written by the user")
+			assertNoFile(t, filepath.Join(vault, "Tasks/Old name.md"))
+
+			renamed, ok := vaultState.Get("renamed")
+			require.True(t, ok)
+			assert.Equal(t, "Tasks/New name.md", renamed.Path)
+			assert.Equal(t, state.Hash(pageContent), renamed.Hash)
+			assert.Equal(t, lastRun.Add(time.Hour), renamed.LastEditedTime)
+
+			_, ok = vaultState.Get("removed")
+			assert.False(t, ok)
+			_, ok = vaultState.Get("subtask")
+			assert.False(t, ok)
+
+			test.assertions(t, vault)
+		})
+	}
+}
+
+func assertFileContent(t *testing.T, path, expected string) {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, expected, string(content))
+}
+
+func assertNoFile(t *testing.T, path string) {
+	_, err := os.Stat(path)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "file %s exists", path)
+}
+
+func TestSync_FailedPages(t *testing.T) {
+	srv := notiontest.NewServer(t)
+	srv.AddDatabase(notiontest.Database{ID: "f1000000-0000-0000-0000-000000000001", Title: "Notes"})
+	srv.AddPage(notiontest.Page{
+		ID:     "f1000000-0000-0000-0000-000000000002",
+		Title:  "A",
+		Parent: notiontest.DatabaseParent("f1000000-0000-0000-0000-000000000001"),
+		Content: []notiontest.Block{
+			notiontest.Paragraph("before"),
+			notiontest.MentionPage("f1000000-0000-0000-0000-000000000003", "Missing"),
+		},
+	})
+
+	vault := t.TempDir()
+
+	migrate := func() []error {
+		vaultState, err := state.Load(vault)
+		require.NoError(t, err)
+
+		logger, _ := log.MockLogger()
+		m, err := NewMigrator(&config.Config{
+			DatabaseID:  "f1000000-0000-0000-0000-000000000001",
+			VaultPath:   vault,
+			Sync:        true,
+			SyncRemoved: config.SyncRemovedDelete,
+		}, NewCache(), logger, WithNotionHTTPClient(srv.Client()), WithState(vaultState))
+		require.NoError(t, err)
+
+		ctx := context.TODO()
+
+		pages, err := m.FetchPages(ctx)
+		require.NoError(t, err)
+
+		errs := []error{}
+		for _, page := range pages {
+			if err = m.FetchParseAndSavePage(ctx, page, map[string]bool{}); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		require.NoError(t, m.WritePagesToDisk(ctx))
+		require.NoError(t, vaultState.Save())
+
+		return errs
+	}
+
+	// The mentioned page is missing, the page is neither written nor recorded.
+	assert.Len(t, migrate(), 1)
+	assertNoFile(t, filepath.Join(vault, "Notes/A.md"))
+
+	vaultState, err := state.Load(vault)
+	require.NoError(t, err)
+	_, ok := vaultState.Get("f1000000-0000-0000-0000-000000000002")
+	assert.False(t, ok)
+
+	// The next sync migrates the page again, even though it was not edited.
+	srv.AddPage(notiontest.Page{ID: "f1000000-0000-0000-0000-000000000003", Title: "Missing"})
+
+	assert.Empty(t, migrate())
+	assertFileContent(t, filepath.Join(vault, "Notes/A.md"), "before\n[[Missing.md]]\n")
+}
+
+func TestSync_SharedMentions(t *testing.T) {
+	srv := notiontest.NewServer(t)
+	srv.AddDatabase(notiontest.Database{ID: "f2000000-0000-0000-0000-000000000001", Title: "Notes"})
+	srv.AddPage(notiontest.Page{ID: "f2000000-0000-0000-0000-000000000002", Title: "Shared"})
+	for _, page := range []struct{ id, title string }{
+		{"f2000000-0000-0000-0000-000000000003", "A"},
+		{"f2000000-0000-0000-0000-000000000004", "B"},
+	} {
+		srv.AddPage(notiontest.Page{
+			ID:      page.id,
+			Title:   page.title,
+			Parent:  notiontest.DatabaseParent("f2000000-0000-0000-0000-000000000001"),
+			Content: []notiontest.Block{notiontest.MentionPage("f2000000-0000-0000-0000-000000000002", "Shared")},
+		})
+	}
+
+	vault := t.TempDir()
+
+	migrate := func() {
+		vaultState, err := state.Load(vault)
+		require.NoError(t, err)
+
+		logger, _ := log.MockLogger()
+		m, err := NewMigrator(&config.Config{
+			DatabaseID:  "f2000000-0000-0000-0000-000000000001",
+			VaultPath:   vault,
+			Sync:        true,
+			SyncRemoved: config.SyncRemovedDelete,
+		}, NewCache(), logger, WithNotionHTTPClient(srv.Client()), WithState(vaultState))
+		require.NoError(t, err)
+
+		ctx := context.TODO()
+
+		pages, err := m.FetchPages(ctx)
+		require.NoError(t, err)
+
+		for _, page := range pages {
+			require.NoError(t, m.FetchParseAndSavePage(ctx, page, map[string]bool{}))
+		}
+
+		require.NoError(t, m.WritePagesToDisk(ctx))
+		require.NoError(t, vaultState.Save())
+	}
+
+	// removeMention edits a page so it no longer mentions the shared page.
+	removeMention := func(id, title string) {
+		srv.AddPage(notiontest.Page{
+			ID:             id,
+			Title:          title,
+			Parent:         notiontest.DatabaseParent("f2000000-0000-0000-0000-000000000001"),
+			LastEditedTime: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			Content:        []notiontest.Block{notiontest.Paragraph("No mention")},
+		})
+	}
+
+	migrate()
+	assertFileContent(t, filepath.Join(vault, "Notes/A.md"), "[[Shared.md]]\n")
+	assertFileContent(t, filepath.Join(vault, "Notes/B.md"), "[[Shared.md]]\n")
+	assert.FileExists(t, filepath.Join(vault, "Shared.md"))
+
+	// B was not edited and still mentions the shared page, it is kept.
+	removeMention("f2000000-0000-0000-0000-000000000003", "A")
+	migrate()
+	assertFileContent(t, filepath.Join(vault, "Notes/A.md"), "No mention\n")
+	assert.FileExists(t, filepath.Join(vault, "Shared.md"))
+
+	// No page mentions the shared page anymore.
+	removeMention("f2000000-0000-0000-0000-000000000004", "B")
+	migrate()
+	assertFileContent(t, filepath.Join(vault, "Notes/B.md"), "No mention\n")
+	assertNoFile(t, filepath.Join(vault, "Shared.md"))
+}
+
+func TestSync_FilteredSource(t *testing.T) {
+	lastRun := time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC)
+	vault := t.TempDir()
+
+	for notePath, content := range map[string]string{
+		"Tasks/Done.md":             "done content",
+		"Tasks/Open.md":             "open content",
+		"Tasks/Done/Old subtask.md": "old subtask content",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(vault, notePath)), 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(vault, notePath), []byte(content), 0600))
+	}
+
+	vaultState, err := state.Load(vault)
+	require.NoError(t, err)
+	vaultState.Set("done", state.Entry{Path: "Tasks/Done.md", LastEditedTime: lastRun, Parent: "database:000000"})
+	vaultState.Set("open", state.Entry{Path: "Tasks/Open.md", LastEditedTime: lastRun, Parent: "database:000000"})
+	vaultState.Set("old-subtask", state.Entry{Path: "Tasks/Done/Old subtask.md", LastEditedTime: lastRun, Parent: "done"})
+
+	httpClient := &http.Client{
+		Transport: &mockRoundtripper{fn: func(_ *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: 200,
+				Body:       io.NopCloser(bytes.NewReader(mustReadFixture("fixtures/page_blocks.json"))),
+			}, nil
+		}},
+	}
+
+	logger, _ := log.MockLogger()
+
+	filter, err := dbquery.ParseFilter("Status = Done")
+	require.NoError(t, err)
+
+	// The filter only lists the done page, the open page no longer matches it.
+	listed := []*Page{
+		{
+			id:         "done",
+			buffer:     &strings.Builder{},
+			Path:       filepath.Join(vault, "Tasks/Done.md"),
+			notionPage: notion.Page{ID: "done", LastEditedTime: lastRun.Add(time.Hour)},
+		},
+	}
+
+	m := migrator{
+		notionClient: notion.NewClient("secret-api-key", notion.WithHTTPClient(httpClient)),
+		config: &config.Config{
+			DatabaseID:  "000000",
+			VaultPath:   vault,
+			Sync:        true,
+			SyncRemoved: config.SyncRemovedDelete,
+			Filter:      "Status = Done",
+		},
+		cache:  NewCache(),
+		logger: logger,
+		state:  vaultState,
+		listed: listed,
+		filter: filter,
+	}
+
+	ctx := context.TODO()
+
+	m.pages = m.changedPages(ctx, listed)
+	for _, page := range m.pages {
+		require.NoError(t, m.FetchParseAndSavePage(ctx, page, map[string]bool{}))
+	}
+
+	require.NoError(t, m.WritePagesToDisk(ctx))
+
+	// The page filtered out is kept, the child page no longer referenced by the migrated page is deleted.
+	assertFileContent(t, filepath.Join(vault, "Tasks/Open.md"), "open content")
+	_, ok := vaultState.Get("open")
+	assert.True(t, ok)
+	assertNoFile(t, filepath.Join(vault, "Tasks/Done/Old subtask.md"))
+	_, ok = vaultState.Get("old-subtask")
+	assert.False(t, ok)
+}
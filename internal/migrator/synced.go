@@ -0,0 +1,143 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// syncedCopy is a duplicate of a synced block. Whether it embeds the original depends on the note of the page
+// of the original being written, which is only known once every page is fetched. Until then the page holds
+// marker in place of the duplicate.
+type syncedCopy struct {
+	marker     string
+	originalID string
+	pageID     string
+	// inline is the content of the original, written when the note of its page is not written.
+	inline string
+}
+
+// writeSyncedBlock writes a synced block. The original block is written inline followed by an Obsidian block ID,
+// https://help.obsidian.md/Linking+notes+and+files/Internal+links#Link+to+a+block+in+a+note.
+// A duplicate embeds the original, `![[Page#^blockid]]`, when the note of the page of the original is written,
+// otherwise the content of the original is written inline. A duplicate whose original is not found is written
+// as a warning callout.
+func (m *migrator) writeSyncedBlock(ctx context.Context, parentPage *Page, block *notion.SyncedBlock) error {
+	buffer := parentPage.buffer
+
+	if block.SyncedFrom == nil {
+		// The empty lines make the content its own Markdown block, the block ID refers to the block before it.
+		buffer.WriteString("\n")
+		if err := m.writeChrildren(ctx, parentPage, block, ""); err != nil {
+			return err
+		}
+		buffer.WriteString("\n" + obsidianBlockID(block.ID()) + "\n\n")
+
+		return nil
+	}
+
+	originalID := block.SyncedFrom.BlockID
+
+	original, err := m.notionClient.FindBlockByID(ctx, originalID)
+	if err != nil {
+		m.logger.Warn(fmt.Sprintf(
+			"Original synced block `%s` of page `%s` not found, make sure its page is shared with the integration. error: %s",
+			originalID,
+			m.removeObsidianVault(parentPage.Path),
+			err.Error(),
+		))
+		fmt.Fprintf(buffer, "> [!warning] Synced block %s not found\n\n", originalID)
+
+		return nil
+	}
+
+	pageID, err := m.resolveParentID(ctx, original.Parent())
+	if err != nil {
+		return fmt.Errorf("failed to find the page of the original synced block %s. error: %w", originalID, err)
+	}
+
+	inline, err := m.capture(parentPage, func() error {
+		return m.writeChrildren(ctx, parentPage, original, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	marker := "%%n2o-synced-" + block.ID() + "%%"
+	parentPage.syncedCopies = append(parentPage.syncedCopies, syncedCopy{
+		marker:     marker,
+		originalID: originalID,
+		pageID:     pageID,
+		inline:     inline,
+	})
+	buffer.WriteString(marker + "\n")
+
+	return nil
+}
+
+// resolveSyncedCopies replaces the markers of the duplicates of synced blocks. A duplicate embeds the original
+// when the note of its page is written, or kept from the last sync, and inlines its content otherwise.
+func (m *migrator) resolveSyncedCopies() {
+	pages := map[*Page]bool{}
+	notes := map[string]*Page{}
+
+	var walk func(page *Page)
+	walk = func(page *Page) {
+		if pages[page] {
+			return
+		}
+		pages[page] = true
+		if page.Path != "" && !m.hasFailed(page.id) {
+			notes[page.id] = page
+		}
+		for _, child := range page.children {
+			walk(child)
+		}
+	}
+	for _, page := range m.pages {
+		walk(page)
+	}
+
+	for page := range pages {
+		if len(page.syncedCopies) == 0 {
+			continue
+		}
+
+		content := page.buffer.String()
+		for _, syncedCopy := range page.syncedCopies {
+			replacement := syncedCopy.inline
+			if original, ok := notes[syncedCopy.pageID]; ok {
+				replacement = fmt.Sprintf("![[%s#%s]]\n", original.title, obsidianBlockID(syncedCopy.originalID))
+			}
+			content = replaceMarker(content, syncedCopy.marker, replacement)
+		}
+
+		page.buffer = &strings.Builder{}
+		page.buffer.WriteString(content)
+		page.syncedCopies = nil
+	}
+}
+
+// replaceMarker replaces the line of marker with replacement. The text before the marker on its line is the
+// prefix of the nesting of the duplicate, every line of the replacement gets it.
+func replaceMarker(content, marker, replacement string) string {
+	start := strings.Index(content, marker)
+	if start == -1 {
+		return content
+	}
+
+	lineStart := strings.LastIndex(content[:start], "\n") + 1
+	end := start + len(marker)
+	if end < len(content) && content[end] == '\n' {
+		end++
+	}
+
+	return content[:lineStart] + prefixLines(replacement, content[lineStart:start]) + content[end:]
+}
+
+// obsidianBlockID returns the Obsidian block ID of a Notion block, `^` and the block ID without dashes.
+func obsidianBlockID(blockID string) string {
+	return "^" + strings.ReplaceAll(blockID, "-", "")
+}
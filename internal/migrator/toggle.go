@@ -0,0 +1,63 @@
+package migrator
+
+import (
+	"context"
+	"html"
+
+	"github.com/GustavoCaso/n2o/internal/config"
+	"github.com/dstotijn/go-notion"
+)
+
+// writeToggle writes a toggle as configured by config.Toggles.
+func (m *migrator) writeToggle(ctx context.Context, parentPage *Page, block *notion.ToggleBlock) error {
+	buffer := parentPage.buffer
+
+	switch m.config.Toggles {
+	case config.TogglesCallout:
+		text, err := m.capture(parentPage, func() error {
+			return m.writeRichText(ctx, parentPage, block.RichText)
+		})
+		if err != nil {
+			return err
+		}
+
+		// The text is the title of the folded callout, the lines after the first one are part of its body.
+		buffer.WriteString(prefixLines("[!"+config.DefaultCalloutType+"]- "+text+"\n", quotePrefix))
+		if err = m.writeChrildren(ctx, parentPage, block, quotePrefix); err != nil {
+			return err
+		}
+		// The empty line ends the callout, otherwise the next callout or paragraph would be part of it.
+		buffer.WriteString("\n")
+	case config.TogglesDetails:
+		// Obsidian does not render Markdown inside HTML, the summary is the escaped plain text of the toggle.
+		// The empty lines around the children let Obsidian render them as Markdown.
+		summary := html.EscapeString(extractPlainTextFromRichText(block.RichText))
+		buffer.WriteString("<details>\n<summary>" + summary + "</summary>\n\n")
+		if err := m.writeChrildren(ctx, parentPage, block, ""); err != nil {
+			return err
+		}
+		buffer.WriteString("\n</details>\n")
+	default:
+		buffer.WriteString("- ")
+		if err := m.writeRichText(ctx, parentPage, block.RichText); err != nil {
+			return err
+		}
+		buffer.WriteString("\n")
+		if err := m.writeChrildren(ctx, parentPage, block, listIndent); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// headingChildrenPrefix returns the prefix of the children of a heading. When toggles are folded, the children
+// of toggleable headings are written below them: Obsidian folds the content of a heading, and the heading
+// stays in the outline and in the `[[note#heading]]` links.
+func (m *migrator) headingChildrenPrefix(toggleable bool) string {
+	if toggleable && (m.config.Toggles == config.TogglesCallout || m.config.Toggles == config.TogglesDetails) {
+		return ""
+	}
+
+	return listIndent
+}
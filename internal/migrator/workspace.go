@@ -0,0 +1,255 @@
+package migrator
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/dstotijn/go-notion"
+)
+
+// maxBlockParents limits how many blocks we walk up to find the page or database containing a block.
+const maxBlockParents = 20
+
+// workspaceNode is a page or a database returned by the Notion search endpoint.
+type workspaceNode struct {
+	id         string
+	parentID   string
+	title      string
+	notionPage *notion.Page
+	page       *Page
+}
+
+// workspaceTree places the pages and databases of a workspace in folders mirroring the Notion hierarchy.
+type workspaceTree struct {
+	nodes      map[string]*workspaceNode
+	rootFolder string
+	// folders stores the folder for the children of every node, visiting marks the nodes being resolved
+	// to stop on malformed hierarchies.
+	folders  map[string]string
+	visiting map[string]bool
+	// claim reserves the path of a page note, named marks the pages whose name is final.
+	claim func(pageID, pagePath string) string
+	named map[string]bool
+}
+
+// fetchWorkspacePages returns every page the integration has access to.
+// The pages are stored in folders mirroring the Notion hierarchy and saved in the cache,
+// so pages reached from a mention and from the search are only migrated once.
+func (m *migrator) fetchWorkspacePages(ctx context.Context) ([]*Page, error) {
+	results, err := m.searchWorkspace(ctx)
+	if err != nil {
+		return []*Page{}, fmt.Errorf("failed to search the workspace. error: %w", err)
+	}
+
+	tree := &workspaceTree{
+		nodes:      map[string]*workspaceNode{},
+		rootFolder: m.config.VaultFilepath(),
+		folders:    map[string]string{},
+		visiting:   map[string]bool{},
+		claim:      m.uniquePath,
+		named:      map[string]bool{},
+	}
+	// The results are sorted by the search endpoint, we keep that order for the pages.
+	order := []string{}
+
+	for _, result := range results {
+		switch object := result.(type) {
+		case notion.Page:
+			if object.Archived {
+				continue
+			}
+			parentID, parentErr := m.resolveParentID(ctx, object.Parent)
+			if parentErr != nil {
+				return []*Page{}, parentErr
+			}
+			notionPage := object
+			tree.nodes[object.ID] = &workspaceNode{
+				id:         object.ID,
+				parentID:   parentID,
+				title:      m.extractPageTitle(object),
+				notionPage: &notionPage,
+			}
+			order = append(order, object.ID)
+		case notion.Database:
+			if object.Archived {
+				continue
+			}
+			parentID, parentErr := m.resolveParentID(ctx, object.Parent)
+			if parentErr != nil {
+				return []*Page{}, parentErr
+			}
+			tree.nodes[object.ID] = &workspaceNode{
+				id:       object.ID,
+				parentID: parentID,
+				title:    m.sanitizeName(extractPlainTextFromRichText(object.Title)),
+			}
+		}
+	}
+
+	notionPages := make([]notion.Page, 0, len(order))
+	databasePages := map[string][]notion.Page{}
+	for _, id := range order {
+		node := tree.nodes[id]
+		notionPages = append(notionPages, *node.notionPage)
+		databasePages[node.parentID] = append(databasePages[node.parentID], *node.notionPage)
+	}
+
+	// The search already listed the pages of the databases, the child database blocks do not query them again.
+	for _, node := range tree.nodes {
+		if node.notionPage != nil {
+			continue
+		}
+		db := m.childDatabase(node.id)
+		db.once.Do(func() {
+			db.title = node.title
+			db.pages = databasePages[node.id]
+		})
+	}
+	// Pages with the same name are told apart by their ID, the oldest page keeps the name.
+	for _, notionPage := range creationOrder(notionPages) {
+		tree.pagePath(tree.nodes[notionPage.ID])
+	}
+
+	pages := make([]*Page, 0, len(order))
+
+	for _, id := range order {
+		node := tree.nodes[id]
+		pagePath := tree.pagePath(node)
+
+		page := &Page{
+			id:         node.id,
+			buffer:     &strings.Builder{},
+			title:      m.vaultLink(pagePath),
+			Path:       pagePath,
+			notionPage: *node.notionPage,
+		}
+
+		page.coverPhoto = coverImage(node.notionPage.Cover)
+
+		node.page = page
+		pages = append(pages, page)
+	}
+
+	for _, page := range pages {
+		page.parent = tree.parentPage(tree.nodes[page.id])
+		m.cache.Set(page.id, page)
+	}
+
+	return pages, nil
+}
+
+// searchWorkspace returns every page and database shared with the integration.
+func (m *migrator) searchWorkspace(ctx context.Context) (notion.SearchResults, error) {
+	opts := &notion.SearchOpts{}
+
+	notionResponse, err := m.notionClient.Search(ctx, opts)
+	if err != nil {
+		return notion.SearchResults{}, err
+	}
+
+	result := notion.SearchResults{}
+
+	result = append(result, notionResponse.Results...)
+
+	for notionResponse.HasMore && notionResponse.NextCursor != nil {
+		opts.StartCursor = *notionResponse.NextCursor
+
+		notionResponse, err = m.notionClient.Search(ctx, opts)
+		if err != nil {
+			return notion.SearchResults{}, err
+		}
+
+		result = append(result, notionResponse.Results...)
+	}
+
+	return result, nil
+}
+
+// resolveParentID returns the ID of the page or database containing an object.
+// Pages created inside a block (for example a column) have the block as parent,
+// we walk up the blocks until we find the page or database.
+func (m *migrator) resolveParentID(ctx context.Context, parent notion.Parent) (string, error) {
+	for range maxBlockParents {
+		switch parent.Type {
+		case notion.ParentTypePage:
+			return parent.PageID, nil
+		case notion.ParentTypeDatabase:
+			return parent.DatabaseID, nil
+		case notion.ParentTypeWorkspace:
+			return "", nil
+		case notion.ParentTypeBlock:
+			block, err := m.notionClient.FindBlockByID(ctx, parent.BlockID)
+			if err != nil {
+				return "", fmt.Errorf("failed to find parent block %s: %w", parent.BlockID, err)
+			}
+			parent = block.Parent()
+		default:
+			return "", nil
+		}
+	}
+
+	return "", nil
+}
+
+// parentFolder returns the folder where node is stored.
+// Nodes whose parent is not shared with the integration are stored at the root of the vault folder.
+func (t *workspaceTree) parentFolder(node *workspaceNode) string {
+	parent, ok := t.nodes[node.parentID]
+	if !ok {
+		return t.rootFolder
+	}
+
+	return t.childrenFolder(parent)
+}
+
+// childrenFolder returns the folder for the children of node.
+// The children of a page are stored in a folder named after the page,
+// and the pages of a database in a folder named after the database.
+func (t *workspaceTree) childrenFolder(node *workspaceNode) string {
+	if folder, ok := t.folders[node.id]; ok {
+		return folder
+	}
+
+	if t.visiting[node.id] {
+		return t.rootFolder
+	}
+	t.visiting[node.id] = true
+	defer delete(t.visiting, node.id)
+
+	folder := path.Join(t.parentFolder(node), node.title)
+	if node.notionPage != nil {
+		folder = strings.TrimSuffix(t.pagePath(node), ".md")
+	}
+	t.folders[node.id] = folder
+
+	return folder
+}
+
+// pagePath returns the path of the note of a page node. The path is claimed the first time,
+// a page with the same name as another page gets the short page ID added to its name.
+func (t *workspaceTree) pagePath(node *workspaceNode) string {
+	folder := t.parentFolder(node)
+
+	if !t.named[node.id] {
+		t.named[node.id] = true
+		node.title = strings.TrimPrefix(t.claim(node.id, path.Join(folder, node.title)), folder+"/")
+	}
+
+	return path.Join(folder, node.title)
+}
+
+// parentPage returns the closest page containing node. The pages of a database belong to the page containing it.
+func (t *workspaceTree) parentPage(node *workspaceNode) *Page {
+	visited := map[string]bool{node.id: true}
+
+	for parent, ok := t.nodes[node.parentID]; ok && !visited[parent.id]; parent, ok = t.nodes[parent.parentID] {
+		if parent.page != nil {
+			return parent.page
+		}
+		visited[parent.id] = true
+	}
+
+	return nil
+}
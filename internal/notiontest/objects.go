@@ -0,0 +1,384 @@
+package notiontest
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultTime is the created and last edited time of the objects without one.
+var defaultTime = time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC)
+
+const defaultUserID = "00000000-0000-0000-0000-000000000000"
+
+// Parent is the page, database, block or workspace containing an object.
+type Parent struct {
+	Type string
+	ID   string
+}
+
+func WorkspaceParent() Parent {
+	return Parent{Type: "workspace"}
+}
+
+func PageParent(id string) Parent {
+	return Parent{Type: "page_id", ID: id}
+}
+
+func DatabaseParent(id string) Parent {
+	return Parent{Type: "database_id", ID: id}
+}
+
+func BlockParent(id string) Parent {
+	return Parent{Type: "block_id", ID: id}
+}
+
+func (p Parent) json() map[string]any {
+	if p.Type == "workspace" {
+		return map[string]any{"type": "workspace", "workspace": true}
+	}
+
+	return map[string]any{"type": p.Type, p.Type: p.ID}
+}
+
+// Page is a Notion page. The pages of a database have a DatabaseParent.
+type Page struct {
+	ID     string
+	Title  string
+	Parent Parent
+	// Properties are the database properties of the page in the Notion API format, without the title.
+	Properties map[string]any
+	// Cover is the URL of the cover image, a URL returned by AddFile is a file uploaded to Notion.
+	Cover          string
+	LastEditedTime time.Time
+	Archived       bool
+	Content        []Block
+}
+
+// Database is a Notion database.
+type Database struct {
+	ID     string
+	Title  string
+	Parent Parent
+	// TitleProperty is the name of the title property, `Name` by default.
+	TitleProperty string
+	// Properties is the schema of the database in the Notion API format, without the title.
+	Properties     map[string]any
+	LastEditedTime time.Time
+	Archived       bool
+}
+
+func (d *Database) json() map[string]any {
+	titleProperty := d.TitleProperty
+	if titleProperty == "" {
+		titleProperty = "Name"
+	}
+
+	properties := map[string]any{}
+	for name, value := range d.Properties {
+		properties[name] = value
+	}
+	properties[titleProperty] = map[string]any{
+		"id":    "title",
+		"name":  titleProperty,
+		"type":  "title",
+		"title": map[string]any{},
+	}
+
+	return map[string]any{
+		"object":           "database",
+		"id":               d.ID,
+		"created_time":     formatTime(d.LastEditedTime),
+		"last_edited_time": formatTime(d.LastEditedTime),
+		"title":            RichText(d.Title),
+		"description":      []any{},
+		"properties":       properties,
+		"parent":           d.Parent.json(),
+		"url":              "https://www.notion.so/" + d.ID,
+		"archived":         d.Archived,
+		"is_inline":        d.Parent.Type != "workspace",
+	}
+}
+
+// User is a person in the workspace.
+type User struct {
+	ID    string
+	Name  string
+	Email string
+}
+
+func (u *User) json() map[string]any {
+	return map[string]any{
+		"object":     "user",
+		"id":         u.ID,
+		"type":       "person",
+		"name":       u.Name,
+		"avatar_url": nil,
+		"person":     map[string]any{"email": u.Email},
+	}
+}
+
+// Block is a Notion block. Data is the content of the block for its type, in the Notion API format.
+// Blocks without ID get one generated from their parent and position.
+type Block struct {
+	ID       string
+	Type     string
+	Data     map[string]any
+	Children []Block
+
+	parent Parent
+}
+
+func NewBlock(blockType string, data map[string]any, children ...Block) Block {
+	return Block{Type: blockType, Data: data, Children: children}
+}
+
+func Paragraph(text string, children ...Block) Block {
+	return NewBlock("paragraph", textData(RichText(text)), children...)
+}
+
+func Heading1(text string) Block {
+	return NewBlock("heading_1", textData(RichText(text)))
+}
+
+func Heading2(text string) Block {
+	return NewBlock("heading_2", textData(RichText(text)))
+}
+
+func Heading3(text string) Block {
+	return NewBlock("heading_3", textData(RichText(text)))
+}
+
+// ToggleHeading is a toggleable heading_1, heading_2 or heading_3 with children.
+func ToggleHeading(level int, text string, children ...Block) Block {
+	data := textData(RichText(text))
+	data["is_toggleable"] = true
+	return NewBlock(fmt.Sprintf("heading_%d", level), data, children...)
+}
+
+func BulletedListItem(text string, children ...Block) Block {
+	return NewBlock("bulleted_list_item", textData(RichText(text)), children...)
+}
+
+func NumberedListItem(text string, children ...Block) Block {
+	return NewBlock("numbered_list_item", textData(RichText(text)), children...)
+}
+
+func ToDo(text string, checked bool) Block {
+	data := textData(RichText(text))
+	data["checked"] = checked
+	return NewBlock("to_do", data)
+}
+
+func Toggle(text string, children ...Block) Block {
+	return NewBlock("toggle", textData(RichText(text)), children...)
+}
+
+func Quote(text string, children ...Block) Block {
+	return NewBlock("quote", textData(RichText(text)), children...)
+}
+
+// Callout is a callout with an icon, see EmojiIcon and ExternalIcon, and a color. A nil icon is a callout without icon.
+func Callout(icon map[string]any, color, text string, children ...Block) Block {
+	data := textData(RichText(text))
+	data["icon"] = icon
+	data["color"] = color
+	return NewBlock("callout", data, children...)
+}
+
+func EmojiIcon(emoji string) map[string]any {
+	return map[string]any{"type": "emoji", "emoji": emoji}
+}
+
+func ExternalIcon(url string) map[string]any {
+	return map[string]any{"type": "external", "external": map[string]any{"url": url}}
+}
+
+// SyncedBlock is an original synced block, its ID is the block ID the duplicates are synced from.
+func SyncedBlock(id string, children ...Block) Block {
+	return Block{ID: id, Type: "synced_block", Data: map[string]any{"synced_from": nil}, Children: children}
+}
+
+// SyncedBlockCopy is a duplicate of the original synced block with ID originalID.
+func SyncedBlockCopy(originalID string) Block {
+	return NewBlock("synced_block", map[string]any{
+		"synced_from": map[string]any{"type": "block_id", "block_id": originalID},
+	})
+}
+
+func Divider() Block {
+	return NewBlock("divider", map[string]any{})
+}
+
+// ChildPage is the block of a page created inside another page. The page is added with AddPage and a PageParent.
+func ChildPage(pageID, title string) Block {
+	return Block{ID: pageID, Type: "child_page", Data: map[string]any{"title": title}}
+}
+
+// ChildDatabase is the block of a database created inside a page.
+// The database is added with AddDatabase and a PageParent.
+func ChildDatabase(databaseID, title string) Block {
+	return Block{ID: databaseID, Type: "child_database", Data: map[string]any{"title": title}}
+}
+
+func LinkToPage(pageID string) Block {
+	return NewBlock("link_to_page", map[string]any{"type": "page_id", "page_id": pageID})
+}
+
+// MentionPage is a paragraph mentioning a page.
+func MentionPage(pageID, title string) Block {
+	return NewBlock("paragraph", textData([]any{
+		map[string]any{
+			"type": "mention",
+			"mention": map[string]any{
+				"type": "page",
+				"page": map[string]any{"id": pageID},
+			},
+			"annotations": annotations(),
+			"plain_text":  title,
+			"href":        "https://www.notion.so/" + normalizeID(pageID),
+		},
+	}))
+}
+
+// MentionDate is a paragraph mentioning a date, or a date range when end is not empty.
+func MentionDate(start, end string) Block {
+	return NewBlock("paragraph", textData([]any{
+		map[string]any{
+			"type": "mention",
+			"mention": map[string]any{
+				"type": "date",
+				"date": dateData(start, end),
+			},
+			"annotations": annotations(),
+			"plain_text":  start,
+			"href":        nil,
+		},
+	}))
+}
+
+// RichText returns a plain rich text in the Notion API format.
+func RichText(text string) []any {
+	if text == "" {
+		return []any{}
+	}
+
+	return []any{
+		map[string]any{
+			"type":        "text",
+			"text":        map[string]any{"content": text, "link": nil},
+			"annotations": annotations(),
+			"plain_text":  text,
+			"href":        nil,
+		},
+	}
+}
+
+func textData(richText []any) map[string]any {
+	return map[string]any{"rich_text": richText, "color": "default"}
+}
+
+func dateData(start, end string) map[string]any {
+	date := map[string]any{"start": start, "end": nil, "time_zone": nil}
+	if end != "" {
+		date["end"] = end
+	}
+
+	return date
+}
+
+func annotations() map[string]any {
+	return map[string]any{
+		"bold":          false,
+		"italic":        false,
+		"strikethrough": false,
+		"underline":     false,
+		"code":          false,
+		"color":         "default",
+	}
+}
+
+// SelectProperty is the value of a select page property.
+func SelectProperty(name string) map[string]any {
+	return map[string]any{"type": "select", "select": map[string]any{"name": name, "color": "default"}}
+}
+
+// MultiSelectProperty is the value of a multi-select page property.
+func MultiSelectProperty(names ...string) map[string]any {
+	options := []any{}
+	for _, name := range names {
+		options = append(options, map[string]any{"name": name, "color": "default"})
+	}
+
+	return map[string]any{"type": "multi_select", "multi_select": options}
+}
+
+// TextProperty is the value of a rich text page property.
+func TextProperty(text string) map[string]any {
+	return map[string]any{"type": "rich_text", "rich_text": RichText(text)}
+}
+
+// PeopleProperty is the value of a people page property. Like the Notion API for integrations
+// without access to user information, it only includes the IDs of the users.
+func PeopleProperty(userIDs ...string) map[string]any {
+	people := []any{}
+	for _, id := range userIDs {
+		people = append(people, map[string]any{"object": "user", "id": id})
+	}
+
+	return map[string]any{"type": "people", "people": people}
+}
+
+// FormulaProperty is the value of a formula page property, with a string, number, boolean or date result.
+func FormulaProperty(result any) map[string]any {
+	formula := map[string]any{}
+
+	switch v := result.(type) {
+	case string:
+		formula["type"] = "string"
+		formula["string"] = v
+	case bool:
+		formula["type"] = "boolean"
+		formula["boolean"] = v
+	case time.Time:
+		formula["type"] = "date"
+		formula["date"] = map[string]any{"start": v.Format("2006-01-02"), "end": nil, "time_zone": nil}
+	default:
+		formula["type"] = "number"
+		formula["number"] = v
+	}
+
+	return map[string]any{"type": "formula", "formula": formula}
+}
+
+// DateProperty is the value of a date page property, or a date range when end is not empty.
+func DateProperty(start, end string) map[string]any {
+	return map[string]any{"type": "date", "date": dateData(start, end)}
+}
+
+// NumberProperty is the value of a number page property, also used as rollup value.
+func NumberProperty(number float64) map[string]any {
+	return map[string]any{"type": "number", "number": number}
+}
+
+// RelationProperty is the value of a relation page property.
+func RelationProperty(pageIDs ...string) map[string]any {
+	relations := []any{}
+	for _, id := range pageIDs {
+		relations = append(relations, map[string]any{"id": id})
+	}
+
+	return map[string]any{"type": "relation", "relation": relations, "has_more": false}
+}
+
+// RollupProperty is the value of a rollup page property showing the original values, like NumberProperty.
+func RollupProperty(values ...map[string]any) map[string]any {
+	array := []any{}
+	for _, value := range values {
+		array = append(array, value)
+	}
+
+	return map[string]any{
+		"type":   "rollup",
+		"rollup": map[string]any{"type": "array", "array": array, "function": "show_original"},
+	}
+}
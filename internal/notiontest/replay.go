@@ -0,0 +1,107 @@
+package notiontest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GustavoCaso/n2o/internal/cassette"
+)
+
+// ReplayTransport is an http.RoundTripper answering the requests with the interactions recorded in a cassette.
+//
+// Requests are matched by method, URL and body. When the same request was recorded more than once,
+// the responses are replayed in the recorded order and the last one is repeated.
+type ReplayTransport struct {
+	mu       sync.Mutex
+	recorded map[string][]cassette.Response
+	replayed map[string]int
+}
+
+// NewReplayTransport loads the cassette recorded with `-record`.
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	interactions, err := cassette.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ReplayTransport{
+		recorded: map[string][]cassette.Response{},
+		replayed: map[string]int{},
+	}
+
+	for _, interaction := range interactions {
+		key := replayKey(interaction.Request.Method, interaction.Request.URL, interaction.Request.Body)
+		t.recorded[key] = append(t.recorded[key], interaction.Response)
+	}
+
+	return t, nil
+}
+
+// NewReplayClient returns an HTTP client replaying a cassette, to build a notion.Client in tests.
+func NewReplayClient(t testing.TB, path string) *http.Client {
+	t.Helper()
+
+	transport, err := NewReplayTransport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &http.Client{Transport: transport}
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	key := replayKey(req.Method, req.URL.String(), body)
+
+	t.mu.Lock()
+	responses, ok := t.recorded[key]
+	index := t.replayed[key]
+	if index < len(responses)-1 {
+		t.replayed[key]++
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("no recorded interaction for %s %s %s", req.Method, req.URL.String(), body)
+	}
+
+	recorded := responses[index]
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// replayKey compacts JSON bodies, so requests match regardless of the formatting of the cassette.
+func replayKey(method, url string, body []byte) string {
+	compacted := &bytes.Buffer{}
+	if err := json.Compact(compacted, body); err != nil {
+		compacted.Reset()
+		compacted.Write(body)
+	}
+
+	return strings.Join([]string{method, url, compacted.String()}, " ")
+}
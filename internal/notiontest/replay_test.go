@@ -0,0 +1,98 @@
+package notiontest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCassette = `{"request":{"method":"POST","url":"https://api.notion.com/v1/search","body":{"page_size":100}},"response":{"status_code":429,"body":{"object":"error"}}}
+{"request":{"method":"POST","url":"https://api.notion.com/v1/search","body":{"page_size":100}},"response":{"status_code":200,"body":{"object":"list"}}}
+{"request":{"method":"GET","url":"https://api.notion.com/v1/users/000000"},"response":{"status_code":200,"body":{"object":"user"}}}
+`
+
+func TestReplayTransport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(testCassette), 0600))
+
+	client := NewReplayClient(t, path)
+
+	send := func(method, url, body string) (int, string, error) {
+		req, err := http.NewRequestWithContext(context.Background(), method, url, bytes.NewBufferString(body))
+		require.NoError(t, err)
+
+		resp, err := client.Transport.RoundTrip(req)
+		if err != nil {
+			return 0, "", err
+		}
+		defer resp.Body.Close()
+
+		respBody, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return resp.StatusCode, string(respBody), nil
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		url            string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "replays the first recorded response",
+			method:         http.MethodPost,
+			url:            "https://api.notion.com/v1/search",
+			body:           `{"page_size": 100}`,
+			expectedStatus: http.StatusTooManyRequests,
+			expectedBody:   `{"object":"error"}`,
+		},
+		{
+			name:           "replays the responses in the recorded order",
+			method:         http.MethodPost,
+			url:            "https://api.notion.com/v1/search",
+			body:           `{"page_size":100}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"object":"list"}`,
+		},
+		{
+			name:           "repeats the last recorded response",
+			method:         http.MethodPost,
+			url:            "https://api.notion.com/v1/search",
+			body:           `{"page_size":100}`,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"object":"list"}`,
+		},
+		{
+			name:           "replays requests without body",
+			method:         http.MethodGet,
+			url:            "https://api.notion.com/v1/users/000000",
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"object":"user"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			status, body, err := send(test.method, test.url, test.body)
+			require.NoError(t, err)
+			assert.Equal(t, test.expectedStatus, status)
+			assert.Equal(t, test.expectedBody, body)
+		})
+	}
+
+	t.Run("fails for requests not recorded", func(t *testing.T) {
+		_, _, err := send(http.MethodGet, "https://api.notion.com/v1/users/111111", "")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no recorded interaction for GET https://api.notion.com/v1/users/111111")
+	})
+}
@@ -0,0 +1,691 @@
+package notiontest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	// MaxPageSize is the largest page size accepted by the Notion API.
+	MaxPageSize = 100
+	// ReferenceLimit is the number of relations, people and rollup values included in the page properties.
+	// The complete values are returned by the page property endpoint.
+	ReferenceLimit = 25
+	// RichTextLimit is the number of rich text items included in the title and rich_text page properties.
+	RichTextLimit = 100
+)
+
+// Server is a fake Notion API serving an in-memory workspace.
+//
+// It implements the read endpoints used by n2o: retrieving pages, databases, blocks and users,
+// querying databases, listing block children and searching. Lists are paginated like the Notion API,
+// with start_cursor and page_size, so tests can exercise pagination by lowering PageSize.
+// Page properties are truncated like the Notion API, see ReferenceLimit and RichTextLimit.
+// It also serves the files uploaded to Notion, added with AddFile.
+// Database queries return every page of the database, their filters and sorts are recorded, see Queries.
+type Server struct {
+	URL string
+	// PageSize is the number of results returned per page when the request does not ask for less.
+	PageSize int
+
+	server *httptest.Server
+
+	mu        sync.Mutex
+	pages     map[string]*Page
+	databases map[string]*Database
+	blocks    map[string]*Block
+	// children holds the IDs of the child blocks of every page and block, in order.
+	children map[string][]string
+	users    map[string]*User
+	files    map[string]string
+	// order and userOrder keep the objects in insertion order, so the lists are stable between pages.
+	order     []string
+	userOrder []string
+	requests  map[string]int
+	// queries holds the bodies of the queries of every database, in order.
+	queries map[string][]map[string]any
+}
+
+// NewServer starts a fake Notion API, closed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s := &Server{
+		PageSize:  MaxPageSize,
+		pages:     map[string]*Page{},
+		databases: map[string]*Database{},
+		blocks:    map[string]*Block{},
+		children:  map[string][]string{},
+		users:     map[string]*User{},
+		files:     map[string]string{},
+		requests:  map[string]int{},
+		queries:   map[string][]map[string]any{},
+	}
+
+	s.server = httptest.NewServer(http.HandlerFunc(s.handle))
+	s.URL = s.server.URL
+	t.Cleanup(s.server.Close)
+
+	return s
+}
+
+// Client returns an HTTP client sending the requests for the Notion API to the fake server.
+func (s *Server) Client() *http.Client {
+	target, _ := url.Parse(s.URL)
+
+	return &http.Client{
+		Transport: &rewriteTransport{target: target, next: s.server.Client().Transport},
+	}
+}
+
+// Requests returns how many times a request was received, for example `GET /v1/pages/<id>`.
+func (s *Server) Requests(request string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[request]
+}
+
+// Queries returns the bodies of the queries of a database, with their filter, sorts and cursor.
+func (s *Server) Queries(databaseID string) []map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[normalizeID(databaseID)]
+}
+
+// AddPage adds a page and its content to the workspace. A page with the ID of a page already added replaces it,
+// for example to edit a page between two syncs.
+func (s *Server) AddPage(page Page) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if page.Parent.Type == "" {
+		page.Parent = WorkspaceParent()
+	}
+	if page.LastEditedTime.IsZero() {
+		page.LastEditedTime = defaultTime
+	}
+
+	key := normalizeID(page.ID)
+	if _, ok := s.pages[key]; ok {
+		delete(s.children, key)
+	} else {
+		s.order = append(s.order, key)
+	}
+	s.pages[key] = &page
+	s.addChildren(page.ID, "page_id", page.Content)
+}
+
+// AddDatabase adds a database to the workspace. Its pages are added with AddPage and a DatabaseParent.
+func (s *Server) AddDatabase(database Database) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if database.Parent.Type == "" {
+		database.Parent = WorkspaceParent()
+	}
+	if database.LastEditedTime.IsZero() {
+		database.LastEditedTime = defaultTime
+	}
+
+	key := normalizeID(database.ID)
+	s.databases[key] = &database
+	s.order = append(s.order, key)
+}
+
+// AddUser adds a workspace member.
+func (s *Server) AddUser(user User) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := normalizeID(user.ID)
+	s.users[key] = &user
+	s.userOrder = append(s.userOrder, key)
+}
+
+// AddFile adds a file uploaded to Notion and returns its URL, used in file blocks and properties.
+func (s *Server) AddFile(name, content string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files[name] = content
+
+	return s.URL + "/files/" + url.PathEscape(name)
+}
+
+// FilesProperty is the value of a files page property. URLs returned by AddFile are files uploaded to Notion,
+// other URLs are external files.
+func (s *Server) FilesProperty(urls ...string) map[string]any {
+	files := []any{}
+	for _, fileURL := range urls {
+		name := path.Base(fileURL)
+		if strings.HasPrefix(fileURL, s.URL+"/files/") {
+			files = append(files, map[string]any{
+				"name": name,
+				"type": "file",
+				"file": map[string]any{"url": fileURL, "expiry_time": formatTime(defaultTime.Add(time.Hour))},
+			})
+			continue
+		}
+
+		files = append(files, map[string]any{"name": name, "type": "external", "external": map[string]any{"url": fileURL}})
+	}
+
+	return map[string]any{"type": "files", "files": files}
+}
+
+func (s *Server) addChildren(parentID, parentType string, blocks []Block) {
+	for i := range blocks {
+		block := blocks[i]
+		if block.ID == "" {
+			block.ID = fmt.Sprintf("%s-block-%d", parentID, i)
+		}
+		block.parent = Parent{Type: parentType, ID: parentID}
+
+		key := normalizeID(block.ID)
+		s.blocks[key] = &block
+		s.children[normalizeID(parentID)] = append(s.children[normalizeID(parentID)], key)
+		s.addChildren(block.ID, "block_id", block.Children)
+	}
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(segments) == 2 && segments[0] == "files" {
+		s.getFile(w, r, segments[1])
+		return
+	}
+	if len(segments) < 2 || segments[0] != "v1" {
+		writeError(w, http.StatusNotFound, "invalid_request_url", "Invalid request URL.")
+		return
+	}
+	segments = segments[1:]
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[r.Method+" "+r.URL.Path]++
+
+	switch {
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "pages":
+		s.getPage(w, normalizeID(segments[1]))
+	case r.Method == http.MethodGet && len(segments) == 4 && segments[0] == "pages" && segments[2] == "properties":
+		s.getPageProperty(w, r, normalizeID(segments[1]), segments[3])
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "databases":
+		s.getDatabase(w, normalizeID(segments[1]))
+	case r.Method == http.MethodPost && len(segments) == 3 && segments[0] == "databases" && segments[2] == "query":
+		s.queryDatabase(w, r, normalizeID(segments[1]))
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "blocks":
+		s.getBlock(w, normalizeID(segments[1]))
+	case r.Method == http.MethodGet && len(segments) == 3 && segments[0] == "blocks" && segments[2] == "children":
+		s.listChildren(w, r, normalizeID(segments[1]))
+	case r.Method == http.MethodGet && len(segments) == 1 && segments[0] == "users":
+		s.listUsers(w, r)
+	case r.Method == http.MethodGet && len(segments) == 2 && segments[0] == "users":
+		s.getUser(w, normalizeID(segments[1]))
+	case r.Method == http.MethodPost && len(segments) == 1 && segments[0] == "search":
+		s.search(w, r)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid_request_url", "Invalid request URL.")
+	}
+}
+
+func (s *Server) getPage(w http.ResponseWriter, id string) {
+	page, ok := s.pages[id]
+	if !ok {
+		writeNotFound(w, id)
+		return
+	}
+
+	writeJSON(w, s.pageJSON(page))
+}
+
+func (s *Server) getPageProperty(w http.ResponseWriter, r *http.Request, pageID, id string) {
+	page, ok := s.pages[pageID]
+	if !ok {
+		writeNotFound(w, pageID)
+		return
+	}
+
+	if id == "title" {
+		s.writePropertyItems(w, r, id, map[string]any{"type": "title", "title": RichText(page.Title)})
+		return
+	}
+
+	for name, value := range page.Properties {
+		property, ok := value.(map[string]any)
+		if ok && propertyID(name, property) == id {
+			s.writePropertyItems(w, r, id, property)
+			return
+		}
+	}
+
+	writeNotFound(w, id)
+}
+
+// writePropertyItems writes the values of a page property as the page property endpoint does:
+// title, rich_text, relation, people and rollup properties as a paginated list of property items.
+func (s *Server) writePropertyItems(w http.ResponseWriter, r *http.Request, id string, property map[string]any) {
+	propertyType, _ := property["type"].(string)
+	item := map[string]any{"id": id, "type": propertyType, "next_url": nil}
+
+	var values []any
+	switch propertyType {
+	case "title", "rich_text", "relation", "people":
+		values, _ = property[propertyType].([]any)
+		item[propertyType] = map[string]any{}
+	case "rollup":
+		rollup, _ := property["rollup"].(map[string]any)
+		if rollup["type"] == "array" {
+			values, _ = rollup["array"].([]any)
+			item["rollup"] = map[string]any{"type": "array", "array": []any{}, "function": "show_original"}
+		} else {
+			item["rollup"] = rollup
+		}
+	default:
+		writeJSON(w, map[string]any{
+			"object":     "property_item",
+			"id":         id,
+			"type":       propertyType,
+			propertyType: property[propertyType],
+		})
+		return
+	}
+
+	results := []any{}
+	for _, value := range values {
+		result := map[string]any{"object": "property_item", "id": id}
+		if rollupValue, ok := value.(map[string]any); ok && propertyType == "rollup" {
+			for key, data := range rollupValue {
+				result[key] = data
+			}
+		} else {
+			result["type"] = propertyType
+			result[propertyType] = value
+		}
+		results = append(results, result)
+	}
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	list, ok := s.list(w, results, r.URL.Query().Get("start_cursor"), pageSize)
+	if !ok {
+		return
+	}
+
+	list["type"] = "property_item"
+	list["property_item"] = item
+
+	writeJSON(w, list)
+}
+
+func (s *Server) getDatabase(w http.ResponseWriter, id string) {
+	database, ok := s.databases[id]
+	if !ok {
+		writeNotFound(w, id)
+		return
+	}
+
+	writeJSON(w, database.json())
+}
+
+func (s *Server) queryDatabase(w http.ResponseWriter, r *http.Request, id string) {
+	if _, ok := s.databases[id]; !ok {
+		writeNotFound(w, id)
+		return
+	}
+
+	query := struct {
+		StartCursor string `json:"start_cursor"`
+		PageSize    int    `json:"page_size"`
+	}{}
+	body := map[string]any{}
+	if r.ContentLength != 0 {
+		content, err := io.ReadAll(r.Body)
+		if err == nil {
+			err = json.Unmarshal(content, &query)
+		}
+		if err == nil {
+			err = json.Unmarshal(content, &body)
+		}
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+	}
+	s.queries[id] = append(s.queries[id], body)
+
+	results := []any{}
+	for _, pageID := range s.order {
+		page, ok := s.pages[pageID]
+		if ok && !page.Archived && page.Parent.Type == "database_id" && normalizeID(page.Parent.ID) == id {
+			results = append(results, s.pageJSON(page))
+		}
+	}
+
+	s.writeList(w, results, query.StartCursor, query.PageSize, "page_or_database")
+}
+
+func (s *Server) getBlock(w http.ResponseWriter, id string) {
+	block, ok := s.blocks[id]
+	if !ok {
+		writeNotFound(w, id)
+		return
+	}
+
+	writeJSON(w, s.blockJSON(block))
+}
+
+func (s *Server) listChildren(w http.ResponseWriter, r *http.Request, id string) {
+	_, isPage := s.pages[id]
+	_, isBlock := s.blocks[id]
+	if !isPage && !isBlock {
+		writeNotFound(w, id)
+		return
+	}
+
+	results := []any{}
+	for _, childID := range s.children[id] {
+		results = append(results, s.blockJSON(s.blocks[childID]))
+	}
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	s.writeList(w, results, r.URL.Query().Get("start_cursor"), pageSize, "block")
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	results := []any{}
+	for _, id := range s.userOrder {
+		results = append(results, s.users[id].json())
+	}
+
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+	s.writeList(w, results, r.URL.Query().Get("start_cursor"), pageSize, "user")
+}
+
+func (s *Server) getUser(w http.ResponseWriter, id string) {
+	user, ok := s.users[id]
+	if !ok {
+		writeNotFound(w, id)
+		return
+	}
+
+	writeJSON(w, user.json())
+}
+
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests[r.Method+" "+r.URL.Path]++
+
+	content, ok := s.files[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	_, _ = w.Write([]byte(content))
+}
+
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	opts := struct {
+		Query       string `json:"query"`
+		StartCursor string `json:"start_cursor"`
+		PageSize    int    `json:"page_size"`
+		Filter      *struct {
+			Value string `json:"value"`
+		} `json:"filter"`
+	}{}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil {
+			writeError(w, http.StatusBadRequest, "validation_error", err.Error())
+			return
+		}
+	}
+
+	results := []any{}
+	for _, id := range s.order {
+		if page, ok := s.pages[id]; ok && !page.Archived {
+			if (opts.Filter == nil || opts.Filter.Value == "page") && matches(page.Title, opts.Query) {
+				results = append(results, s.pageJSON(page))
+			}
+		}
+		if database, ok := s.databases[id]; ok && !database.Archived {
+			if (opts.Filter == nil || opts.Filter.Value == "database") && matches(database.Title, opts.Query) {
+				results = append(results, database.json())
+			}
+		}
+	}
+
+	s.writeList(w, results, opts.StartCursor, opts.PageSize, "page_or_database")
+}
+
+// writeList writes a page of results. The cursor is the position of the first result of the page.
+func (s *Server) writeList(w http.ResponseWriter, results []any, cursor string, pageSize int, resultType string) {
+	list, ok := s.list(w, results, cursor, pageSize)
+	if !ok {
+		return
+	}
+
+	list["type"] = resultType
+	list[resultType] = map[string]any{}
+
+	writeJSON(w, list)
+}
+
+// list returns a page of results, or writes an error when the cursor is invalid.
+func (s *Server) list(w http.ResponseWriter, results []any, cursor string, pageSize int) (map[string]any, bool) {
+	start := 0
+	if cursor != "" {
+		var err error
+		start, err = strconv.Atoi(cursor)
+		if err != nil || start < 0 || start > len(results) {
+			writeError(w, http.StatusBadRequest, "validation_error", "start_cursor is invalid.")
+			return nil, false
+		}
+	}
+
+	size := s.PageSize
+	if pageSize > 0 && pageSize < size {
+		size = pageSize
+	}
+	if size <= 0 || size > MaxPageSize {
+		size = MaxPageSize
+	}
+
+	end := min(start+size, len(results))
+
+	var nextCursor *string
+	if end < len(results) {
+		next := strconv.Itoa(end)
+		nextCursor = &next
+	}
+
+	return map[string]any{
+		"object":      "list",
+		"results":     results[start:end],
+		"has_more":    nextCursor != nil,
+		"next_cursor": nextCursor,
+	}, true
+}
+
+func (s *Server) pageJSON(page *Page) map[string]any {
+	properties := map[string]any{}
+
+	if page.Parent.Type == "database_id" {
+		titleProperty := "Name"
+		if database, ok := s.databases[normalizeID(page.Parent.ID)]; ok && database.TitleProperty != "" {
+			titleProperty = database.TitleProperty
+		}
+		for name, value := range page.Properties {
+			properties[name] = pageProperty(name, value)
+		}
+		properties[titleProperty] = map[string]any{
+			"id":    "title",
+			"type":  "title",
+			"title": RichText(page.Title),
+		}
+	} else {
+		properties["title"] = map[string]any{
+			"id":    "title",
+			"type":  "title",
+			"title": RichText(page.Title),
+		}
+	}
+
+	result := map[string]any{
+		"object":           "page",
+		"id":               page.ID,
+		"created_time":     formatTime(page.LastEditedTime),
+		"last_edited_time": formatTime(page.LastEditedTime),
+		"created_by":       map[string]any{"object": "user", "id": defaultUserID},
+		"last_edited_by":   map[string]any{"object": "user", "id": defaultUserID},
+		"parent":           page.Parent.json(),
+		"archived":         page.Archived,
+		"url":              "https://www.notion.so/" + page.ID,
+		"properties":       properties,
+	}
+
+	switch {
+	case strings.HasPrefix(page.Cover, s.URL+"/files/"):
+		result["cover"] = map[string]any{
+			"type": "file",
+			"file": map[string]any{"url": page.Cover, "expiry_time": formatTime(defaultTime.Add(time.Hour))},
+		}
+	case page.Cover != "":
+		result["cover"] = map[string]any{"type": "external", "external": map[string]any{"url": page.Cover}}
+	}
+
+	return result
+}
+
+// pageProperty returns a page property as included in the page object, with an ID and truncated values.
+func pageProperty(name string, value any) any {
+	property, ok := value.(map[string]any)
+	if !ok {
+		return value
+	}
+
+	result := map[string]any{}
+	for key, data := range property {
+		result[key] = data
+	}
+	result["id"] = propertyID(name, property)
+
+	propertyType, _ := property["type"].(string)
+	switch propertyType {
+	case "relation", "people":
+		if values, ok := property[propertyType].([]any); ok && len(values) > ReferenceLimit {
+			result[propertyType] = values[:ReferenceLimit]
+			result["has_more"] = true
+		}
+	case "title", "rich_text":
+		if values, ok := property[propertyType].([]any); ok && len(values) > RichTextLimit {
+			result[propertyType] = values[:RichTextLimit]
+		}
+	case "rollup":
+		rollup, _ := property["rollup"].(map[string]any)
+		if values, ok := rollup["array"].([]any); ok && len(values) > ReferenceLimit {
+			truncated := map[string]any{}
+			for key, data := range rollup {
+				truncated[key] = data
+			}
+			truncated["array"] = values[:ReferenceLimit]
+			result["rollup"] = truncated
+		}
+	}
+
+	return result
+}
+
+// propertyID returns the ID of a page property, the escaped name when the property does not have one.
+func propertyID(name string, property map[string]any) string {
+	if id, ok := property["id"].(string); ok && id != "" {
+		return id
+	}
+
+	return url.PathEscape(name)
+}
+
+func (s *Server) blockJSON(block *Block) map[string]any {
+	data := map[string]any{}
+	for key, value := range block.Data {
+		data[key] = value
+	}
+
+	return map[string]any{
+		"object":           "block",
+		"id":               block.ID,
+		"type":             block.Type,
+		"created_time":     formatTime(defaultTime),
+		"last_edited_time": formatTime(defaultTime),
+		"created_by":       map[string]any{"object": "user", "id": defaultUserID},
+		"last_edited_by":   map[string]any{"object": "user", "id": defaultUserID},
+		"parent":           block.parent.json(),
+		"has_children":     len(s.children[normalizeID(block.ID)]) > 0,
+		"archived":         false,
+		block.Type:         data,
+	}
+}
+
+func matches(title, query string) bool {
+	return query == "" || strings.Contains(strings.ToLower(title), strings.ToLower(query))
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeNotFound(w http.ResponseWriter, id string) {
+	writeError(
+		w,
+		http.StatusNotFound,
+		"object_not_found",
+		fmt.Sprintf("Could not find object with ID: %s. Make sure the relevant pages and databases are shared.", id),
+	)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"object":  "error",
+		"status":  status,
+		"code":    code,
+		"message": message,
+	})
+}
+
+// rewriteTransport sends the requests to the fake server, keeping their path and query.
+type rewriteTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	clone := req.Clone(req.Context())
+	clone.URL.Scheme = t.target.Scheme
+	clone.URL.Host = t.target.Host
+	clone.Host = t.target.Host
+
+	return t.next.RoundTrip(clone)
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+// normalizeID removes the dashes from a Notion ID, the API accepts and returns both forms.
+func normalizeID(id string) string {
+	return strings.ReplaceAll(id, "-", "")
+}
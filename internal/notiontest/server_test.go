@@ -0,0 +1,142 @@
+package notiontest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dstotijn/go-notion"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	srv := NewServer(t)
+	srv.PageSize = 2
+
+	srv.AddDatabase(Database{ID: "db-1", Title: "Tasks", TitleProperty: "Task"})
+	for i := range 5 {
+		srv.AddPage(Page{ID: fmt.Sprintf("task-%d", i), Title: fmt.Sprintf("Task %d", i), Parent: DatabaseParent("db-1")})
+	}
+	srv.AddPage(Page{
+		ID:    "page-1",
+		Title: "Projects",
+		Content: []Block{
+			Heading1("Projects"),
+			BulletedListItem("First", Paragraph("Nested")),
+			ChildPage("page-2", "Roadmap"),
+		},
+	})
+	srv.AddPage(Page{ID: "page-2", Title: "Roadmap", Parent: PageParent("page-1")})
+	srv.AddUser(User{ID: "user-1", Name: "Ada", Email: "ada@example.com"})
+	srv.AddUser(User{ID: "user-2", Name: "Grace", Email: "grace@example.com"})
+
+	client := notion.NewClient("secret-api-key", notion.WithHTTPClient(srv.Client()))
+	ctx := context.Background()
+
+	t.Run("retrieves pages", func(t *testing.T) {
+		page, err := client.FindPageByID(ctx, "page-2")
+		require.NoError(t, err)
+		assert.Equal(t, notion.ParentTypePage, page.Parent.Type)
+		assert.Equal(t, "page-1", page.Parent.PageID)
+
+		properties, ok := page.Properties.(notion.PageProperties)
+		require.True(t, ok)
+		assert.Equal(t, "Roadmap", properties.Title.Title[0].PlainText)
+	})
+
+	t.Run("retrieves databases", func(t *testing.T) {
+		db, err := client.FindDatabaseByID(ctx, "db-1")
+		require.NoError(t, err)
+		assert.Equal(t, "Tasks", db.Title[0].PlainText)
+		assert.Equal(t, notion.DBPropTypeTitle, db.Properties["Task"].Type)
+	})
+
+	t.Run("paginates database queries", func(t *testing.T) {
+		titles := []string{}
+		query := &notion.DatabaseQuery{}
+		for {
+			resp, err := client.QueryDatabase(ctx, "db-1", query)
+			require.NoError(t, err)
+			assert.True(t, len(resp.Results) <= 2)
+
+			for _, page := range resp.Results {
+				properties, ok := page.Properties.(notion.DatabasePageProperties)
+				require.True(t, ok)
+				titles = append(titles, properties["Task"].Title[0].PlainText)
+			}
+
+			if !resp.HasMore {
+				break
+			}
+			query.StartCursor = *resp.NextCursor
+		}
+
+		assert.Equal(t, []string{"Task 0", "Task 1", "Task 2", "Task 3", "Task 4"}, titles)
+		assert.Equal(t, 3, srv.Requests("POST /v1/databases/db-1/query"))
+
+		queries := srv.Queries("db-1")
+		require.Len(t, queries, 3)
+		assert.Equal(t, query.StartCursor, queries[2]["start_cursor"])
+	})
+
+	t.Run("lists block children", func(t *testing.T) {
+		resp, err := client.FindBlockChildrenByID(ctx, "page-1", nil)
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 2)
+		assert.True(t, resp.HasMore)
+
+		list, ok := resp.Results[1].(*notion.BulletedListItemBlock)
+		require.True(t, ok)
+		assert.True(t, list.HasChildren())
+
+		nested, err := client.FindBlockChildrenByID(ctx, list.ID(), nil)
+		require.NoError(t, err)
+		require.Len(t, nested.Results, 1)
+		assert.Equal(t, "Nested", nested.Results[0].(*notion.ParagraphBlock).RichText[0].PlainText)
+
+		next, err := client.FindBlockChildrenByID(ctx, "page-1", &notion.PaginationQuery{StartCursor: *resp.NextCursor})
+		require.NoError(t, err)
+		require.Len(t, next.Results, 1)
+		assert.False(t, next.HasMore)
+
+		child, ok := next.Results[0].(*notion.ChildPageBlock)
+		require.True(t, ok)
+		assert.Equal(t, "page-2", child.ID())
+		assert.Equal(t, "Roadmap", child.Title)
+	})
+
+	t.Run("lists users", func(t *testing.T) {
+		resp, err := client.ListUsers(ctx, &notion.PaginationQuery{PageSize: 1})
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, "Ada", resp.Results[0].Name)
+		assert.True(t, resp.HasMore)
+
+		user, err := client.FindUserByID(ctx, "user-2")
+		require.NoError(t, err)
+		assert.Equal(t, "grace@example.com", user.Person.Email)
+	})
+
+	t.Run("searches pages and databases", func(t *testing.T) {
+		resp, err := client.Search(ctx, &notion.SearchOpts{Query: "task", PageSize: 10})
+		require.NoError(t, err)
+		// PageSize caps the page size asked by the request.
+		assert.Len(t, resp.Results, 2)
+		assert.True(t, resp.HasMore)
+
+		resp, err = client.Search(ctx, &notion.SearchOpts{
+			Filter: &notion.SearchFilter{Property: "object", Value: "database"},
+		})
+		require.NoError(t, err)
+		require.Len(t, resp.Results, 1)
+		_, ok := resp.Results[0].(notion.Database)
+		assert.True(t, ok)
+	})
+
+	t.Run("returns not found errors", func(t *testing.T) {
+		_, err := client.FindPageByID(ctx, "missing")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "object_not_found")
+	})
+}
@@ -0,0 +1,260 @@
+package ratelimit
+
+import (
+	"context"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Notion allows an average of three requests per second for each integration.
+// See: https://developers.notion.com/reference/request-limits
+const (
+	DefaultRequestsPerSecond = 3
+	DefaultBurst             = 3
+	DefaultMaxRetries        = 5
+	DefaultBaseDelay         = time.Second
+	DefaultMaxDelay          = 30 * time.Second
+)
+
+type Options struct {
+	// RequestsPerSecond is the average number of requests sent per second. Zero disables the limit.
+	RequestsPerSecond float64
+	// Burst is the number of requests that can be sent at once before being throttled.
+	Burst int
+	// MaxRetries is the number of times a request is retried after a 429 or 5xx response.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry, it doubles on every retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+func DefaultOptions() Options {
+	return Options{
+		RequestsPerSecond: DefaultRequestsPerSecond,
+		Burst:             DefaultBurst,
+		MaxRetries:        DefaultMaxRetries,
+		BaseDelay:         DefaultBaseDelay,
+		MaxDelay:          DefaultMaxDelay,
+	}
+}
+
+// Transport is an http.RoundTripper throttling the requests with a token bucket.
+// Requests answered with 429 or 5xx are retried with a jittered exponential backoff,
+// honoring the Retry-After header when present.
+type Transport struct {
+	next    http.RoundTripper
+	options Options
+	bucket  *tokenBucket
+	now     func() time.Time
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func NewTransport(next http.RoundTripper, options Options) *Transport {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	if options.Burst <= 0 {
+		options.Burst = 1
+	}
+
+	if options.BaseDelay <= 0 {
+		options.BaseDelay = DefaultBaseDelay
+	}
+
+	if options.MaxDelay < options.BaseDelay {
+		options.MaxDelay = options.BaseDelay
+	}
+
+	t := &Transport{
+		next:    next,
+		options: options,
+		now:     time.Now,
+		sleep:   sleep,
+	}
+
+	t.bucket = &tokenBucket{
+		rate:     options.RequestsPerSecond,
+		capacity: float64(options.Burst),
+		tokens:   float64(options.Burst),
+	}
+
+	return t
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(ctx); err != nil {
+			return nil, err
+		}
+
+		attemptReq, err := rewindRequest(req, attempt)
+		if err != nil {
+			return nil, err
+		}
+
+		resp, err := t.next.RoundTrip(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+
+		if !retryable(resp.StatusCode) || attempt >= t.options.MaxRetries {
+			return resp, nil
+		}
+
+		delay, fromHeader := retryAfter(resp, t.now())
+		if !fromHeader {
+			delay = t.backoff(attempt)
+		} else {
+			// Notion asks every request of the integration to wait, not only this one.
+			t.bucket.pause(t.now().Add(delay))
+		}
+
+		// Drain the body so the connection can be reused.
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		if err = t.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait blocks until the token bucket allows a new request.
+func (t *Transport) wait(ctx context.Context) error {
+	for {
+		delay := t.bucket.reserve(t.now())
+		if delay <= 0 {
+			return nil
+		}
+
+		if err := t.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns the delay before retrying, doubling on every attempt.
+// The delay is jittered between half and the full value to spread the retries of concurrent requests.
+func (t *Transport) backoff(attempt int) time.Duration {
+	delay := t.options.MaxDelay
+	if attempt < 32 {
+		if exponential := t.options.BaseDelay << attempt; exponential > 0 && exponential < delay {
+			delay = exponential
+		}
+	}
+
+	half := delay / 2
+
+	return half + rand.N(delay-half+1)
+}
+
+func retryable(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+// retryAfter parses the Retry-After header, either in seconds or as an HTTP date.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// rewindRequest returns a request that can be sent again, with a fresh body.
+func rewindRequest(req *http.Request, attempt int) (*http.Request, error) {
+	if attempt == 0 || req.Body == nil || req.GetBody == nil {
+		return req, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+
+	clone := req.Clone(req.Context())
+	clone.Body = body
+
+	return clone, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// tokenBucket allows `capacity` requests at once, refilled at `rate` requests per second.
+type tokenBucket struct {
+	mu          sync.Mutex
+	rate        float64
+	capacity    float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+// reserve takes a token from the bucket. When there are no tokens left,
+// it returns how long to wait before trying again.
+func (b *tokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Before(b.pausedUntil) {
+		return b.pausedUntil.Sub(now)
+	}
+
+	if b.rate <= 0 {
+		return 0
+	}
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// pause stops every request until the given time.
+func (b *tokenBucket) pause(until time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
@@ -0,0 +1,258 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRoundtripper struct {
+	fn func(*http.Request) (*http.Response, error)
+}
+
+func (m *mockRoundtripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	return m.fn(r)
+}
+
+// fakeClock records the sleeps instead of waiting.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(_ context.Context, d time.Duration) error {
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func newTestTransport(options Options, fn func(*http.Request) (*http.Response, error)) (*Transport, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC)}
+	transport := NewTransport(&mockRoundtripper{fn: fn}, options)
+	transport.now = clock.Now
+	transport.sleep = clock.Sleep
+
+	return transport, clock
+}
+
+func response(statusCode int, headers map[string]string) *http.Response {
+	header := http.Header{}
+	for key, value := range headers {
+		header.Set(key, value)
+	}
+
+	return &http.Response{
+		StatusCode: statusCode,
+		Status:     http.StatusText(statusCode),
+		Header:     header,
+		Body:       io.NopCloser(bytes.NewReader([]byte("{}"))),
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name             string
+		options          Options
+		responses        []*http.Response
+		expectedStatus   int
+		expectedRequests int
+		assertions       func(t *testing.T, clock *fakeClock)
+	}{
+		{
+			name:    "does not retry successful responses",
+			options: Options{MaxRetries: 3},
+			responses: []*http.Response{
+				response(http.StatusOK, nil),
+			},
+			expectedStatus:   http.StatusOK,
+			expectedRequests: 1,
+			assertions: func(t *testing.T, clock *fakeClock) {
+				assert.Empty(t, clock.sleeps)
+			},
+		},
+		{
+			name:    "does not retry client errors",
+			options: Options{MaxRetries: 3},
+			responses: []*http.Response{
+				response(http.StatusBadRequest, nil),
+			},
+			expectedStatus:   http.StatusBadRequest,
+			expectedRequests: 1,
+		},
+		{
+			name:    "honors Retry-After when rate limited",
+			options: Options{MaxRetries: 3},
+			responses: []*http.Response{
+				response(http.StatusTooManyRequests, map[string]string{"Retry-After": "7"}),
+				response(http.StatusOK, nil),
+			},
+			expectedStatus:   http.StatusOK,
+			expectedRequests: 2,
+			assertions: func(t *testing.T, clock *fakeClock) {
+				assert.Equal(t, []time.Duration{7 * time.Second}, clock.sleeps)
+			},
+		},
+		{
+			name:    "retries server errors with exponential backoff",
+			options: Options{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Minute},
+			responses: []*http.Response{
+				response(http.StatusBadGateway, nil),
+				response(http.StatusServiceUnavailable, nil),
+				response(http.StatusInternalServerError, nil),
+				response(http.StatusOK, nil),
+			},
+			expectedStatus:   http.StatusOK,
+			expectedRequests: 4,
+			assertions: func(t *testing.T, clock *fakeClock) {
+				require.Len(t, clock.sleeps, 3)
+				for attempt, delay := range clock.sleeps {
+					maxDelay := time.Second << attempt
+					assert.True(t, delay >= maxDelay/2, "delay %s shorter than %s", delay, maxDelay/2)
+					assert.True(t, delay <= maxDelay, "delay %s longer than %s", delay, maxDelay)
+				}
+			},
+		},
+		{
+			name:    "caps the backoff to the max delay",
+			options: Options{MaxRetries: 2, BaseDelay: time.Second, MaxDelay: 1500 * time.Millisecond},
+			responses: []*http.Response{
+				response(http.StatusInternalServerError, nil),
+				response(http.StatusInternalServerError, nil),
+				response(http.StatusOK, nil),
+			},
+			expectedStatus:   http.StatusOK,
+			expectedRequests: 3,
+			assertions: func(t *testing.T, clock *fakeClock) {
+				require.Len(t, clock.sleeps, 2)
+				assert.True(t, clock.sleeps[1] <= 1500*time.Millisecond, "delay %s is not capped", clock.sleeps[1])
+			},
+		},
+		{
+			name:    "returns the last response after the max retries",
+			options: Options{MaxRetries: 2},
+			responses: []*http.Response{
+				response(http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}),
+				response(http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}),
+				response(http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}),
+			},
+			expectedStatus:   http.StatusTooManyRequests,
+			expectedRequests: 3,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			requests := 0
+			transport, clock := newTestTransport(test.options, func(r *http.Request) (*http.Response, error) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.JSONEq(t, `{"page_size":100}`, string(body))
+
+				resp := test.responses[requests]
+				requests++
+				return resp, nil
+			})
+
+			req, err := http.NewRequestWithContext(
+				context.Background(),
+				http.MethodPost,
+				"https://api.notion.com/v1/search",
+				bytes.NewBufferString(`{"page_size":100}`),
+			)
+			require.NoError(t, err)
+
+			resp, err := transport.RoundTrip(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, resp.StatusCode)
+			assert.Equal(t, test.expectedRequests, requests)
+
+			if test.assertions != nil {
+				test.assertions(t, clock)
+			}
+		})
+	}
+}
+
+func TestRoundTrip_RequestsPerSecond(t *testing.T) {
+	transport, clock := newTestTransport(
+		Options{RequestsPerSecond: 2, Burst: 2},
+		func(_ *http.Request) (*http.Response, error) {
+			return response(http.StatusOK, nil), nil
+		},
+	)
+
+	for range 4 {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://api.notion.com/v1/users", nil)
+		require.NoError(t, err)
+
+		resp, err := transport.RoundTrip(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// The first two requests use the burst, the next ones wait half a second each.
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, clock.sleeps)
+}
+
+func TestRoundTrip_RetryAfterPausesEveryRequest(t *testing.T) {
+	responses := []*http.Response{
+		response(http.StatusTooManyRequests, map[string]string{"Retry-After": "3"}),
+		response(http.StatusOK, nil),
+	}
+	requests := 0
+
+	transport, clock := newTestTransport(
+		Options{MaxRetries: 1},
+		func(_ *http.Request) (*http.Response, error) {
+			resp := responses[requests]
+			requests++
+			return resp, nil
+		},
+	)
+
+	// Another request is sent while the first one waits for the Retry-After.
+	transport.sleep = func(ctx context.Context, d time.Duration) error {
+		if len(clock.sleeps) == 0 {
+			delay := transport.bucket.reserve(clock.now)
+			assert.Equal(t, 3*time.Second, delay)
+		}
+		return clock.Sleep(ctx, d)
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, "https://api.notion.com/v1/users", nil)
+	require.NoError(t, err)
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestRoundTrip_ContextCanceled(t *testing.T) {
+	transport := NewTransport(&mockRoundtripper{fn: func(_ *http.Request) (*http.Response, error) {
+		return response(http.StatusServiceUnavailable, nil), nil
+	}}, Options{MaxRetries: 3, BaseDelay: time.Hour})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.notion.com/v1/users", nil)
+	require.NoError(t, err)
+
+	_, err = transport.RoundTrip(req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error %v", err)
+}
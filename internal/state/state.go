@@ -0,0 +1,210 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Dir is the folder inside the Obsidian vault where n2o stores its files.
+const Dir = ".n2o"
+
+const fileName = "state.json"
+const version = 1
+
+// Entry is a Notion page migrated to the Obsidian vault.
+type Entry struct {
+	// LastEditedTime is the Notion last_edited_time of the page when it was migrated.
+	LastEditedTime time.Time `json:"last_edited_time"`
+	// Path is the location of the page relative to the Obsidian vault.
+	Path string `json:"path"`
+	// Hash is the SHA-256 of the page content written to the vault.
+	Hash string `json:"hash"`
+	// Parent is the ID of the page that references the page, or the source that migrated it.
+	Parent string `json:"parent"`
+	// References are the IDs of the other pages that reference the page, for example by mentioning it.
+	References []string `json:"references,omitempty"`
+}
+
+// ReferencedBy reports if the page is referenced by parent.
+func (e Entry) ReferencedBy(parent string) bool {
+	if e.Parent == parent {
+		return true
+	}
+
+	for _, reference := range e.References {
+		if reference == parent {
+			return true
+		}
+	}
+
+	return false
+}
+
+// State is the manifest of the pages migrated to an Obsidian vault.
+// It is safe for concurrent use.
+type State struct {
+	path  string
+	mu    sync.Mutex
+	pages map[string]Entry
+}
+
+type manifest struct {
+	Version int              `json:"version"`
+	Pages   map[string]Entry `json:"pages"`
+}
+
+// Load reads the state stored in the Obsidian vault.
+// A vault without state returns an empty state.
+func Load(vaultPath string) (*State, error) {
+	s := &State{
+		path:  filepath.Join(vaultPath, Dir, fileName),
+		pages: map[string]Entry{},
+	}
+
+	content, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state file %s. error: %w", s.path, err)
+	}
+
+	m := manifest{}
+	if err = json.Unmarshal(content, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse the state file %s. error: %w", s.path, err)
+	}
+
+	if m.Version != version {
+		return nil, fmt.Errorf("unsupported state file version %d in %s", m.Version, s.path)
+	}
+
+	if m.Pages != nil {
+		s.pages = m.Pages
+	}
+
+	return s, nil
+}
+
+// Save writes the state to the Obsidian vault.
+// The file is replaced atomically so an interrupted run does not corrupt the previous state.
+func (s *State) Save() error {
+	s.mu.Lock()
+	content, err := json.MarshalIndent(manifest{Version: version, Pages: s.pages}, "", "  ")
+	s.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode the state. error: %w", err)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return fmt.Errorf("failed to create the state folder. error: %w", err)
+	}
+
+	tmp := s.path + ".tmp"
+	if err = os.WriteFile(tmp, content, 0600); err != nil {
+		return fmt.Errorf("failed to write the state file %s. error: %w", tmp, err)
+	}
+
+	if err = os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("failed to replace the state file %s. error: %w", s.path, err)
+	}
+
+	return nil
+}
+
+func (s *State) Get(id string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.pages[id]
+	return entry, ok
+}
+
+func (s *State) Set(id string, entry Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pages[id] = entry
+}
+
+func (s *State) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pages, id)
+}
+
+// Reference records that parent references the page with the ID. Pages not in the state are ignored.
+func (s *State) Reference(id, parent string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.pages[id]
+	if !ok || entry.ReferencedBy(parent) {
+		return
+	}
+
+	entry.References = append(entry.References, parent)
+	s.pages[id] = entry
+}
+
+// Unreference records that parent no longer references the page with the ID,
+// and reports if other pages still reference it. The first of the other references becomes the parent.
+func (s *State) Unreference(id, parent string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.pages[id]
+	if !ok {
+		return false
+	}
+
+	references := []string{}
+	for _, reference := range entry.References {
+		if reference != parent {
+			references = append(references, reference)
+		}
+	}
+
+	if entry.Parent == parent {
+		entry.Parent = ""
+		if len(references) > 0 {
+			entry.Parent = references[0]
+			references = references[1:]
+		}
+	}
+
+	entry.References = nil
+	if len(references) > 0 {
+		entry.References = references
+	}
+	s.pages[id] = entry
+
+	return entry.Parent != ""
+}
+
+// Children returns the sorted IDs of the pages referenced by parent.
+func (s *State) Children(parent string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := []string{}
+	for id, entry := range s.pages {
+		if entry.ReferencedBy(parent) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// Hash returns the hash stored in the state for a page content.
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
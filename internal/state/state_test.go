@@ -0,0 +1,84 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("vault without state", func(t *testing.T) {
+		s, err := Load(t.TempDir())
+		require.NoError(t, err)
+
+		_, ok := s.Get("000000")
+		assert.False(t, ok)
+	})
+
+	t.Run("save and load", func(t *testing.T) {
+		vault := t.TempDir()
+		entry := Entry{
+			LastEditedTime: time.Date(2024, 10, 10, 10, 0, 0, 0, time.UTC),
+			Path:           "Meetings/Weekly.md",
+			Hash:           Hash("# Weekly"),
+			Parent:         "database:111111",
+		}
+
+		s, err := Load(vault)
+		require.NoError(t, err)
+		s.Set("000000", entry)
+		require.NoError(t, s.Save())
+
+		assert.FileExists(t, filepath.Join(vault, ".n2o", "state.json"))
+
+		loaded, err := Load(vault)
+		require.NoError(t, err)
+
+		got, ok := loaded.Get("000000")
+		require.True(t, ok)
+		assert.Equal(t, entry, got)
+		assert.Equal(t, []string{"000000"}, loaded.Children("database:111111"))
+
+		loaded.Delete("000000")
+		assert.Empty(t, loaded.Children("database:111111"))
+	})
+
+	t.Run("references", func(t *testing.T) {
+		s, err := Load(t.TempDir())
+		require.NoError(t, err)
+
+		s.Set("000000", Entry{Path: "Shared.md", Parent: "111111"})
+		s.Reference("000000", "222222")
+		s.Reference("000000", "111111")
+		s.Reference("333333", "111111")
+		assert.Equal(t, []string{"000000"}, s.Children("111111"))
+		assert.Equal(t, []string{"000000"}, s.Children("222222"))
+
+		assert.True(t, s.Unreference("000000", "111111"))
+		got, _ := s.Get("000000")
+		assert.Equal(t, Entry{Path: "Shared.md", Parent: "222222"}, got)
+		assert.Empty(t, s.Children("111111"))
+
+		assert.False(t, s.Unreference("000000", "222222"))
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		vault := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(vault, Dir), 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(vault, Dir, "state.json"), []byte(`{"version": 2}`), 0600))
+
+		_, err := Load(vault)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported state file version 2")
+	})
+}
+
+func TestHash(t *testing.T) {
+	assert.Equal(t, Hash("# Weekly"), Hash("# Weekly"))
+	assert.NotEqual(t, Hash("# Weekly"), Hash("# Weekly\n"))
+	assert.Len(t, Hash(""), 64)
+}
@@ -12,14 +12,25 @@ import (
 
 type Job struct {
 	Path string
-	Run  func()
+	// ID identifies what the job works on, for example the Notion page ID, in the errors.
+	ID  string
+	Run func() error
 }
 
+// ErrJob is the error returned by a job.
 type ErrJob struct {
 	Job *Job
 	Err error
 }
 
+func (e *ErrJob) Error() string {
+	return fmt.Sprintf("%s: %v", e.Job.Path, e.Err)
+}
+
+func (e *ErrJob) Unwrap() error {
+	return e.Err
+}
+
 type Option interface {
 	OnCreate(description string)
 	OnAdd(total int)
@@ -77,6 +88,10 @@ type WorkerPool struct {
 	options   []Option
 	wg        sync.WaitGroup
 	totalJobs int
+	// jobs keeps the jobs in the order they were added, errs holds the error of every failed job.
+	jobs []*Job
+	mu   sync.Mutex
+	errs map[*Job]error
 }
 
 func New(description string, workerPoolSize int, opts ...Option) *WorkerPool {
@@ -90,6 +105,7 @@ func New(description string, workerPoolSize int, opts ...Option) *WorkerPool {
 		},
 		options: opts,
 		pool:    make(chan struct{}, workerPoolSize),
+		errs:    map[*Job]error{},
 	}
 }
 
@@ -97,6 +113,7 @@ func (w *WorkerPool) AddJobs(jobs []*Job) {
 	total := len(jobs)
 	w.queue.wg.Add(total)
 	w.totalJobs = total
+	w.jobs = append(w.jobs, jobs...)
 
 	for _, opt := range w.options {
 		opt.OnAdd(total)
@@ -114,7 +131,9 @@ func (w *WorkerPool) AddJobs(jobs []*Job) {
 	}()
 }
 
-func (w *WorkerPool) DoWork(ctx context.Context) {
+// DoWork runs the jobs and waits for them to finish. It returns the errors of the failed jobs,
+// in the order the jobs were added.
+func (w *WorkerPool) DoWork(ctx context.Context) []*ErrJob {
 	w.wg.Add(w.totalJobs)
 
 	go func() {
@@ -131,11 +150,27 @@ func (w *WorkerPool) DoWork(ctx context.Context) {
 							opt.OnDone()
 						}
 					}()
-					j.Run()
+					if err := j.Run(); err != nil {
+						w.mu.Lock()
+						w.errs[j] = err
+						w.mu.Unlock()
+					}
 				}(job)
 			}
 		}
 	}()
 
 	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	errs := []*ErrJob{}
+	for _, job := range w.jobs {
+		if err, ok := w.errs[job]; ok {
+			errs = append(errs, &ErrJob{Job: job, Err: err})
+		}
+	}
+
+	return errs
 }
@@ -2,12 +2,14 @@ package workerpool
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/schollz/progressbar/v3"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type testprogressBarOption struct {
@@ -31,19 +33,25 @@ func (p *testprogressBarOption) OnDone() {
 	p.progressBar.Add(1)
 }
 
+var errBroken = errors.New("broken")
+
 func TestWorkerPool(t *testing.T) {
 	option := &testprogressBarOption{}
 	pool := New("testing", 10, option)
 
 	job1 := &Job{
 		Path: "failed",
-		Run: func() {
+		ID:   "page-1",
+		Run: func() error {
+			return errBroken
 		},
 	}
 
 	job2 := &Job{
 		Path: "success",
-		Run: func() {
+		ID:   "page-2",
+		Run: func() error {
+			return nil
 		},
 	}
 
@@ -56,7 +64,12 @@ func TestWorkerPool(t *testing.T) {
 
 	pool.AddJobs(jobs)
 
-	pool.DoWork(ctx)
+	errs := pool.DoWork(ctx)
+
+	require.Len(t, errs, 1)
+	assert.True(t, errs[0].Job == job1)
+	assert.True(t, errors.Is(errs[0], errBroken))
+	assert.Equal(t, "failed: broken", errs[0].Error())
 
 	result := strings.TrimSpace(option.buf.String())
 